@@ -0,0 +1,209 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// ArithmeticOperation selects the element-wise operation applied by Arithmetic.
+type ArithmeticOperation int
+
+const (
+	ArithmeticAdd      ArithmeticOperation = iota // a + b
+	ArithmeticSubtract                            // a - b
+	ArithmeticMultiply                            // a * b / maxval, so that multiplying by maxval keeps the image
+	ArithmeticMin                                 // Smaller of a and b
+	ArithmeticMax                                 // Larger of a and b
+	ArithmeticAbsDiff                             // |a - b|
+)
+
+// OverflowMode selects what happens to results outside of 0..maxval.
+type OverflowMode int
+
+const (
+	OverflowSaturate OverflowMode = iota // Clamp the result to 0..maxval
+	OverflowWrap                         // Take the result modulo maxval + 1
+)
+
+// Arithmetic combines the PGM image with another image of the same size, pixel by pixel. Both images are first
+// rescaled to the output maximum value, which is the one of the PGM image when maxValue is 0.
+func (pgm *PGM) Arithmetic(other *PGM, operation ArithmeticOperation, mode OverflowMode, maxValue int) error {
+	maxValue, err := checkArithmetic(pgm.width, pgm.height, other.width, other.height, pgm.max, operation, mode, maxValue)
+	if err != nil {
+		return err
+	}
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pgm.data[y][x] = arithmeticValue(pgm.data[y][x], pgm.max, other.data[y][x], other.max, operation, mode, maxValue)
+		}
+	}
+	pgm.max = maxValue
+	return nil
+}
+
+// ArithmeticConstant combines every pixel of the PGM image with a constant given relative to the maximum value of
+// the image, as Arithmetic does with an image.
+func (pgm *PGM) ArithmeticConstant(value uint8, operation ArithmeticOperation, mode OverflowMode, maxValue int) error {
+	constant := &PGM{data: make([][]uint8, pgm.height), width: pgm.width, height: pgm.height, magicNumber: pgm.magicNumber, max: pgm.max}
+	for y := range constant.data {
+		constant.data[y] = make([]uint8, pgm.width)
+		for x := range constant.data[y] {
+			constant.data[y][x] = value
+		}
+	}
+	return pgm.Arithmetic(constant, operation, mode, maxValue)
+}
+
+// Arithmetic combines the PPM image with another image of the same size, channel by channel. Both images are first
+// rescaled to the output maximum value, which is the one of the PPM image when maxValue is 0.
+func (ppm *PPM) Arithmetic(other *PPM, operation ArithmeticOperation, mode OverflowMode, maxValue int) error {
+	maxValue, err := checkArithmetic(ppm.width, ppm.height, other.width, other.height, ppm.max, operation, mode, maxValue)
+	if err != nil {
+		return err
+	}
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			a, b := ppm.data[y][x], other.data[y][x]
+			ppm.data[y][x] = Pixel{
+				R: arithmeticValue(a.R, ppm.max, b.R, other.max, operation, mode, maxValue),
+				G: arithmeticValue(a.G, ppm.max, b.G, other.max, operation, mode, maxValue),
+				B: arithmeticValue(a.B, ppm.max, b.B, other.max, operation, mode, maxValue),
+			}
+		}
+	}
+	ppm.max = maxValue
+	return nil
+}
+
+// ArithmeticConstant combines every pixel of the PPM image with a constant color given relative to the maximum
+// value of the image, as Arithmetic does with an image.
+func (ppm *PPM) ArithmeticConstant(value Pixel, operation ArithmeticOperation, mode OverflowMode, maxValue int) error {
+	constant := &PPM{data: make([][]Pixel, ppm.height), width: ppm.width, height: ppm.height, magicNumber: ppm.magicNumber, max: ppm.max}
+	for y := range constant.data {
+		constant.data[y] = make([]Pixel, ppm.width)
+		for x := range constant.data[y] {
+			constant.data[y][x] = value
+		}
+	}
+	return ppm.Arithmetic(constant, operation, mode, maxValue)
+}
+
+// AveragePGM returns the pixel-wise mean of PGM images of the same size, with the maximum value of the first one.
+func AveragePGM(images []*PGM) (*PGM, error) {
+	if len(images) == 0 {
+		return nil, fmt.Errorf("No image to average")
+	}
+	first := images[0]
+	sums := make([][]float64, first.height)
+	for y := range sums {
+		sums[y] = make([]float64, first.width)
+	}
+	for _, image := range images {
+		if image.width != first.width || image.height != first.height {
+			return nil, fmt.Errorf("Cannot average a %dx%d image with a %dx%d image", image.width, image.height, first.width, first.height)
+		}
+		scale := float64(first.max) / float64(image.max)
+		for y := range sums {
+			for x := range sums[y] {
+				sums[y][x] += float64(image.data[y][x]) * scale
+			}
+		}
+	}
+
+	average := &PGM{data: make([][]uint8, first.height), width: first.width, height: first.height, magicNumber: first.magicNumber, max: first.max}
+	for y := range average.data {
+		average.data[y] = make([]uint8, first.width)
+		for x := range average.data[y] {
+			average.data[y][x] = uint8(math.Round(sums[y][x] / float64(len(images))))
+		}
+	}
+	return average, nil
+}
+
+// AveragePPM returns the pixel-wise mean of PPM images of the same size, with the maximum value of the first one.
+func AveragePPM(images []*PPM) (*PPM, error) {
+	if len(images) == 0 {
+		return nil, fmt.Errorf("No image to average")
+	}
+	first := images[0]
+	sums := make([][][3]float64, first.height)
+	for y := range sums {
+		sums[y] = make([][3]float64, first.width)
+	}
+	for _, image := range images {
+		if image.width != first.width || image.height != first.height {
+			return nil, fmt.Errorf("Cannot average a %dx%d image with a %dx%d image", image.width, image.height, first.width, first.height)
+		}
+		scale := float64(first.max) / float64(image.max)
+		for y := range sums {
+			for x := range sums[y] {
+				pixel := image.data[y][x]
+				sums[y][x][0] += float64(pixel.R) * scale
+				sums[y][x][1] += float64(pixel.G) * scale
+				sums[y][x][2] += float64(pixel.B) * scale
+			}
+		}
+	}
+
+	average := &PPM{data: make([][]Pixel, first.height), width: first.width, height: first.height, magicNumber: first.magicNumber, max: first.max}
+	count := float64(len(images))
+	for y := range average.data {
+		average.data[y] = make([]Pixel, first.width)
+		for x := range average.data[y] {
+			sum := sums[y][x]
+			average.data[y][x] = Pixel{uint8(math.Round(sum[0] / count)), uint8(math.Round(sum[1] / count)), uint8(math.Round(sum[2] / count))}
+		}
+	}
+	return average, nil
+}
+
+// checkArithmetic validates the arguments of Arithmetic and returns the output maximum value.
+func checkArithmetic(width, height, otherWidth, otherHeight, imageMax int, operation ArithmeticOperation, mode OverflowMode, maxValue int) (int, error) {
+	if width != otherWidth || height != otherHeight {
+		return 0, fmt.Errorf("Cannot combine a %dx%d image with a %dx%d image", width, height, otherWidth, otherHeight)
+	}
+	if operation < ArithmeticAdd || operation > ArithmeticAbsDiff {
+		return 0, fmt.Errorf("Unsupported arithmetic operation: %d", operation)
+	}
+	if mode != OverflowSaturate && mode != OverflowWrap {
+		return 0, fmt.Errorf("Unsupported overflow mode: %d", mode)
+	}
+	if maxValue == 0 {
+		maxValue = imageMax
+	}
+	if maxValue < 1 || maxValue > 255 {
+		return 0, fmt.Errorf("Invalid output maximum value: %d (must be between 1 and 255)", maxValue)
+	}
+	return maxValue, nil
+}
+
+// arithmeticValue applies the operation to two values, after rescaling them from their maximum values to maxValue.
+func arithmeticValue(a uint8, aMax int, b uint8, bMax int, operation ArithmeticOperation, mode OverflowMode, maxValue int) uint8 {
+	x := int(math.Round(float64(a) * float64(maxValue) / float64(aMax)))
+	y := int(math.Round(float64(b) * float64(maxValue) / float64(bMax)))
+
+	var result int
+	switch operation {
+	case ArithmeticAdd:
+		result = x + y
+	case ArithmeticSubtract:
+		result = x - y
+	case ArithmeticMultiply:
+		result = int(math.Round(float64(x) * float64(y) / float64(maxValue)))
+	case ArithmeticMin:
+		result = min(x, y)
+	case ArithmeticMax:
+		result = max(x, y)
+	case ArithmeticAbsDiff:
+		result = abs(x - y)
+	}
+
+	if mode == OverflowWrap {
+		result %= maxValue + 1
+		if result < 0 {
+			result += maxValue + 1
+		}
+		return uint8(result)
+	}
+	return uint8(max(0, min(result, maxValue)))
+}
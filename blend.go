@@ -0,0 +1,135 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// BlendMode selects how the values of a top layer are combined with the values of a base image.
+type BlendMode int
+
+const (
+	BlendNormal     BlendMode = iota // Top layer only
+	BlendMultiply                    // Product of the layers, always darker
+	BlendScreen                      // Inverted product of the inverted layers, always lighter
+	BlendOverlay                     // Multiply on the dark parts of the base, screen on its light parts
+	BlendSoftLight                   // Gentle darkening or lightening depending on the top layer
+	BlendHardLight                   // Overlay with the layers swapped
+	BlendDarken                      // Darker of the two layers
+	BlendLighten                     // Lighter of the two layers
+	BlendColorDodge                  // Base brightened to reflect the top layer
+	BlendColorBurn                   // Base darkened to reflect the top layer
+	BlendDifference                  // Absolute difference of the layers
+	BlendExclusion                   // Lower-contrast difference
+)
+
+// Blend combines the top image with the PPM image channel by channel using the blend mode. Opacity, from 0 to 1,
+// mixes the blended result with the original image. Both images must have the same size; their values are
+// compared relative to their own maximum value and the result keeps the maximum value of the PPM image.
+func (ppm *PPM) Blend(top *PPM, mode BlendMode, opacity float64) error {
+	if err := checkBlend(ppm.width, ppm.height, top.width, top.height, mode, opacity); err != nil {
+		return err
+	}
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			base, layer := ppm.data[y][x], top.data[y][x]
+			ppm.data[y][x] = Pixel{
+				R: blendValues(base.R, ppm.max, layer.R, top.max, mode, opacity),
+				G: blendValues(base.G, ppm.max, layer.G, top.max, mode, opacity),
+				B: blendValues(base.B, ppm.max, layer.B, top.max, mode, opacity),
+			}
+		}
+	}
+	return nil
+}
+
+// Blend combines the top image with the PGM image using the blend mode, as PPM.Blend does.
+func (pgm *PGM) Blend(top *PGM, mode BlendMode, opacity float64) error {
+	if err := checkBlend(pgm.width, pgm.height, top.width, top.height, mode, opacity); err != nil {
+		return err
+	}
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			pgm.data[y][x] = blendValues(pgm.data[y][x], pgm.max, top.data[y][x], top.max, mode, opacity)
+		}
+	}
+	return nil
+}
+
+// checkBlend validates the arguments of Blend.
+func checkBlend(width, height, topWidth, topHeight int, mode BlendMode, opacity float64) error {
+	if width != topWidth || height != topHeight {
+		return fmt.Errorf("Cannot blend a %dx%d image with a %dx%d image", topWidth, topHeight, width, height)
+	}
+	if mode < BlendNormal || mode > BlendExclusion {
+		return fmt.Errorf("Unsupported blend mode: %d", mode)
+	}
+	if opacity < 0 || opacity > 1 {
+		return fmt.Errorf("Invalid opacity: %v (must be between 0 and 1)", opacity)
+	}
+	return nil
+}
+
+// blendValues blends a top value onto a base value, each relative to its maximum value, and returns the result
+// relative to the maximum value of the base.
+func blendValues(base uint8, baseMax int, top uint8, topMax int, mode BlendMode, opacity float64) uint8 {
+	a, b := float64(base)/float64(baseMax), float64(top)/float64(topMax)
+	blended := blendChannel(a, b, mode)
+	result := a + opacity*(blended-a)
+	return uint8(math.Round(math.Max(0, math.Min(result, 1)) * float64(baseMax)))
+}
+
+// blendChannel applies the blend mode to a base value a and a top value b, both between 0 and 1.
+// The formulas are those of the W3C compositing specification.
+func blendChannel(a, b float64, mode BlendMode) float64 {
+	switch mode {
+	case BlendMultiply:
+		return a * b
+	case BlendScreen:
+		return a + b - a*b
+	case BlendOverlay:
+		return blendChannel(b, a, BlendHardLight)
+	case BlendSoftLight:
+		if b <= 0.5 {
+			return a - (1-2*b)*a*(1-a)
+		}
+		var d float64
+		if a <= 0.25 {
+			d = ((16*a-12)*a + 4) * a
+		} else {
+			d = math.Sqrt(a)
+		}
+		return a + (2*b-1)*(d-a)
+	case BlendHardLight:
+		if b <= 0.5 {
+			return a * 2 * b
+		}
+		return blendChannel(a, 2*b-1, BlendScreen)
+	case BlendDarken:
+		return math.Min(a, b)
+	case BlendLighten:
+		return math.Max(a, b)
+	case BlendColorDodge:
+		if a == 0 {
+			return 0
+		}
+		if b == 1 {
+			return 1
+		}
+		return math.Min(1, a/(1-b))
+	case BlendColorBurn:
+		if a == 1 {
+			return 1
+		}
+		if b == 0 {
+			return 0
+		}
+		return 1 - math.Min(1, (1-a)/b)
+	case BlendDifference:
+		return math.Abs(a - b)
+	case BlendExclusion:
+		return a + b - 2*a*b
+	default: // BlendNormal
+		return b
+	}
+}
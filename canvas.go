@@ -0,0 +1,302 @@
+package Netpbm
+
+import (
+	"fmt"
+)
+
+// Margins gives a number of pixels for each side of an image.
+type Margins struct {
+	Top, Right, Bottom, Left int
+}
+
+// UniformMargins returns margins of the same size on every side.
+func UniformMargins(size int) Margins {
+	return Margins{Top: size, Right: size, Bottom: size, Left: size}
+}
+
+// Pad adds margins around the PBM image. The new pixels follow the edge mode: EdgeZero fills them with the
+// fill value, EdgeClamp repeats the edge pixels, EdgeMirror reflects the image and EdgeWrap tiles it.
+func (pbm *PBM) Pad(margins Margins, mode EdgeMode, fill bool) error {
+	data, err := padPlane(pbm.data, pbm.width, pbm.height, margins, mode, fill)
+	if err != nil {
+		return err
+	}
+	pbm.data, pbm.width, pbm.height = data, pbm.width+margins.Left+margins.Right, pbm.height+margins.Top+margins.Bottom
+	return nil
+}
+
+// Pad adds margins around the PGM image. The new pixels follow the edge mode: EdgeZero fills them with the
+// fill value, EdgeClamp repeats the edge pixels, EdgeMirror reflects the image and EdgeWrap tiles it.
+func (pgm *PGM) Pad(margins Margins, mode EdgeMode, fill uint8) error {
+	data, err := padPlane(pgm.data, pgm.width, pgm.height, margins, mode, fill)
+	if err != nil {
+		return err
+	}
+	pgm.data, pgm.width, pgm.height = data, pgm.width+margins.Left+margins.Right, pgm.height+margins.Top+margins.Bottom
+	return nil
+}
+
+// Pad adds margins around the PPM image. The new pixels follow the edge mode: EdgeZero fills them with the
+// fill color, EdgeClamp repeats the edge pixels, EdgeMirror reflects the image and EdgeWrap tiles it.
+func (ppm *PPM) Pad(margins Margins, mode EdgeMode, fill Pixel) error {
+	data, err := padPlane(ppm.data, ppm.width, ppm.height, margins, mode, fill)
+	if err != nil {
+		return err
+	}
+	ppm.data, ppm.width, ppm.height = data, ppm.width+margins.Left+margins.Right, ppm.height+margins.Top+margins.Bottom
+	return nil
+}
+
+// Extend grows the canvas of the PBM image to width x height, keeping the image centered.
+// The new pixels follow the edge mode as in Pad.
+func (pbm *PBM) Extend(width, height int, mode EdgeMode, fill bool) error {
+	margins, err := extendMargins(pbm.width, pbm.height, width, height)
+	if err != nil {
+		return err
+	}
+	return pbm.Pad(margins, mode, fill)
+}
+
+// Extend grows the canvas of the PGM image to width x height, keeping the image centered.
+// The new pixels follow the edge mode as in Pad.
+func (pgm *PGM) Extend(width, height int, mode EdgeMode, fill uint8) error {
+	margins, err := extendMargins(pgm.width, pgm.height, width, height)
+	if err != nil {
+		return err
+	}
+	return pgm.Pad(margins, mode, fill)
+}
+
+// Extend grows the canvas of the PPM image to width x height, keeping the image centered.
+// The new pixels follow the edge mode as in Pad.
+func (ppm *PPM) Extend(width, height int, mode EdgeMode, fill Pixel) error {
+	margins, err := extendMargins(ppm.width, ppm.height, width, height)
+	if err != nil {
+		return err
+	}
+	return ppm.Pad(margins, mode, fill)
+}
+
+// Crop keeps only the part of the PBM image inside the rectangle.
+func (pbm *PBM) Crop(rectangle Rectangle) error {
+	data, err := cropPlane(pbm.data, pbm.width, pbm.height, rectangle)
+	if err != nil {
+		return err
+	}
+	pbm.data, pbm.width, pbm.height = data, rectangle.Width, rectangle.Height
+	return nil
+}
+
+// Crop keeps only the part of the PGM image inside the rectangle.
+func (pgm *PGM) Crop(rectangle Rectangle) error {
+	data, err := cropPlane(pgm.data, pgm.width, pgm.height, rectangle)
+	if err != nil {
+		return err
+	}
+	pgm.data, pgm.width, pgm.height = data, rectangle.Width, rectangle.Height
+	return nil
+}
+
+// Crop keeps only the part of the PPM image inside the rectangle.
+func (ppm *PPM) Crop(rectangle Rectangle) error {
+	data, err := cropPlane(ppm.data, ppm.width, ppm.height, rectangle)
+	if err != nil {
+		return err
+	}
+	ppm.data, ppm.width, ppm.height = data, rectangle.Width, rectangle.Height
+	return nil
+}
+
+// Trim removes the borders having the value of the top left pixel and returns the rectangle that was kept.
+// A uniform or empty image is left unchanged.
+func (pbm *PBM) Trim() Rectangle {
+	return pbm.trimBorders(topLeftValue(pbm.data))
+}
+
+// AutoCrop removes the borders having the most common value of the outermost pixels and returns the rectangle
+// that was kept. Unlike Trim, the background does not depend on a single corner pixel. A uniform or empty image is left unchanged.
+func (pbm *PBM) AutoCrop() Rectangle {
+	return pbm.trimBorders(dominantBorderValue(pbm.data, pbm.width, pbm.height))
+}
+
+// Trim removes the borders whose gray levels differ from the top left pixel by at most tolerance and returns
+// the rectangle that was kept. A uniform or empty image is left unchanged.
+func (pgm *PGM) Trim(tolerance int) Rectangle {
+	return pgm.trimBorders(topLeftValue(pgm.data), tolerance)
+}
+
+// AutoCrop removes the borders whose gray levels differ by at most tolerance from the most common level of the
+// outermost pixels and returns the rectangle that was kept. A uniform or empty image is left unchanged.
+func (pgm *PGM) AutoCrop(tolerance int) Rectangle {
+	return pgm.trimBorders(dominantBorderValue(pgm.data, pgm.width, pgm.height), tolerance)
+}
+
+// Trim removes the borders whose colors are within tolerance (Euclidean distance in RGB) of the top left pixel
+// and returns the rectangle that was kept. A uniform or empty image is left unchanged.
+func (ppm *PPM) Trim(tolerance float64) Rectangle {
+	return ppm.trimBorders(topLeftValue(ppm.data), tolerance)
+}
+
+// AutoCrop removes the borders whose colors are within tolerance of the most common color of the outermost
+// pixels and returns the rectangle that was kept. A uniform or empty image is left unchanged.
+func (ppm *PPM) AutoCrop(tolerance float64) Rectangle {
+	return ppm.trimBorders(dominantBorderValue(ppm.data, ppm.width, ppm.height), tolerance)
+}
+
+// trimBorders crops the PBM image to the pixels different from the background.
+func (pbm *PBM) trimBorders(background bool) Rectangle {
+	bounds, found := contentBounds(pbm.width, pbm.height, func(x, y int) bool {
+		return pbm.data[y][x] == background
+	})
+	if found {
+		pbm.Crop(bounds)
+	}
+	return bounds
+}
+
+// trimBorders crops the PGM image to the pixels farther than tolerance from the background.
+func (pgm *PGM) trimBorders(background uint8, tolerance int) Rectangle {
+	bounds, found := contentBounds(pgm.width, pgm.height, func(x, y int) bool {
+		return abs(int(pgm.data[y][x])-int(background)) <= tolerance
+	})
+	if found {
+		pgm.Crop(bounds)
+	}
+	return bounds
+}
+
+// trimBorders crops the PPM image to the pixels farther than tolerance from the background.
+func (ppm *PPM) trimBorders(background Pixel, tolerance float64) Rectangle {
+	limit := tolerance * tolerance // Compare squared distances
+	bounds, found := contentBounds(ppm.width, ppm.height, func(x, y int) bool {
+		pixel := ppm.data[y][x]
+		dr := float64(pixel.R) - float64(background.R)
+		dg := float64(pixel.G) - float64(background.G)
+		db := float64(pixel.B) - float64(background.B)
+		return dr*dr+dg*dg+db*db <= limit
+	})
+	if found {
+		ppm.Crop(bounds)
+	}
+	return bounds
+}
+
+// contentBounds returns the bounding box of the pixels that are not background. When every pixel is background,
+// it returns the whole image and false.
+func contentBounds(width, height int, isBackground func(x, y int) bool) (Rectangle, bool) {
+	left, top, right, bottom := width, height, -1, -1
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if !isBackground(x, y) {
+				left, right = min(left, x), max(right, x)
+				top, bottom = min(top, y), max(bottom, y)
+			}
+		}
+	}
+	if right < 0 {
+		return Rectangle{Width: width, Height: height}, false
+	}
+	return Rectangle{TopLeft: Point{left, top}, Width: right - left + 1, Height: bottom - top + 1}, true
+}
+
+// topLeftValue returns the top left pixel of a plane, or the zero value for an empty plane.
+func topLeftValue[T any](data [][]T) T {
+	var value T
+	if len(data) > 0 && len(data[0]) > 0 {
+		value = data[0][0]
+	}
+	return value
+}
+
+// dominantBorderValue returns the most common value among the outermost pixels of a plane, or the zero value
+// for an empty plane. Ties are broken in favor of the value met first, clockwise from the top left corner.
+func dominantBorderValue[T comparable](data [][]T, width, height int) T {
+	if width == 0 || height == 0 {
+		var value T
+		return value
+	}
+	counts := make(map[T]int)
+	var order []T
+	count := func(value T) {
+		if counts[value] == 0 {
+			order = append(order, value)
+		}
+		counts[value]++
+	}
+	for x := 0; x < width; x++ {
+		count(data[0][x])
+	}
+	for y := 1; y < height; y++ {
+		count(data[y][width-1])
+	}
+	if height > 1 {
+		for x := width - 2; x >= 0; x-- {
+			count(data[height-1][x])
+		}
+	}
+	if width > 1 {
+		for y := height - 2; y > 0; y-- {
+			count(data[y][0])
+		}
+	}
+
+	best := order[0]
+	for _, value := range order[1:] {
+		if counts[value] > counts[best] {
+			best = value
+		}
+	}
+	return best
+}
+
+// extendMargins returns the margins centering a sourceWidth x sourceHeight image on a width x height canvas.
+func extendMargins(sourceWidth, sourceHeight, width, height int) (Margins, error) {
+	if width < sourceWidth || height < sourceHeight {
+		return Margins{}, fmt.Errorf("Cannot extend a %dx%d image to %dx%d", sourceWidth, sourceHeight, width, height)
+	}
+	left, top := (width-sourceWidth)/2, (height-sourceHeight)/2
+	return Margins{Top: top, Right: width - sourceWidth - left, Bottom: height - sourceHeight - top, Left: left}, nil
+}
+
+// padPlane returns a copy of a plane with margins whose pixels follow the edge mode.
+func padPlane[T any](data [][]T, width, height int, margins Margins, mode EdgeMode, fill T) ([][]T, error) {
+	if margins.Top < 0 || margins.Right < 0 || margins.Bottom < 0 || margins.Left < 0 {
+		return nil, fmt.Errorf("Invalid margins: %+v (must not be negative)", margins)
+	}
+	if mode < EdgeClamp || mode > EdgeZero {
+		return nil, fmt.Errorf("Unsupported edge mode: %d", mode)
+	}
+	if width == 0 || height == 0 {
+		mode = EdgeZero // No pixel to repeat: an empty image is padded with the fill value
+	}
+
+	newWidth := width + margins.Left + margins.Right
+	newHeight := height + margins.Top + margins.Bottom
+	result := make([][]T, newHeight)
+	for y := range result {
+		result[y] = make([]T, newWidth)
+		sy, okY := edgeIndex(y-margins.Top, height, mode)
+		for x := range result[y] {
+			sx, okX := edgeIndex(x-margins.Left, width, mode)
+			if okX && okY {
+				result[y][x] = data[sy][sx]
+			} else {
+				result[y][x] = fill
+			}
+		}
+	}
+	return result, nil
+}
+
+// cropPlane returns a copy of the part of a plane inside the rectangle.
+func cropPlane[T any](data [][]T, width, height int, rectangle Rectangle) ([][]T, error) {
+	left, top := rectangle.TopLeft.X, rectangle.TopLeft.Y
+	if rectangle.Width < 1 || rectangle.Height < 1 || left < 0 || top < 0 || left+rectangle.Width > width || top+rectangle.Height > height {
+		return nil, fmt.Errorf("Crop rectangle %dx%d at (%d, %d) does not fit in the %dx%d image", rectangle.Width, rectangle.Height, left, top, width, height)
+	}
+	result := make([][]T, rectangle.Height)
+	for y := range result {
+		result[y] = append([]T(nil), data[top+y][left:left+rectangle.Width]...)
+	}
+	return result, nil
+}
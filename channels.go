@@ -0,0 +1,155 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// Common matrices for ChannelMixer. Each row gives the weights of R, G and B in one output channel.
+var (
+	IdentityMatrix   = [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}                                                       // Leaves the image unchanged
+	SwapRedBlue      = [3][3]float64{{0, 0, 1}, {0, 1, 0}, {1, 0, 0}}                                                       // Exchanges the red and blue channels
+	SepiaMatrix      = [3][3]float64{{0.393, 0.769, 0.189}, {0.349, 0.686, 0.168}, {0.272, 0.534, 0.131}}                   // Classic sepia tone
+	GrayscaleMatrix  = [3][3]float64{{0.299, 0.587, 0.114}, {0.299, 0.587, 0.114}, {0.299, 0.587, 0.114}}                   // Rec. 601 luma on every channel
+	Grayscale709     = [3][3]float64{{0.2126, 0.7152, 0.0722}, {0.2126, 0.7152, 0.0722}, {0.2126, 0.7152, 0.0722}}          // Rec. 709 luma on every channel
+	AverageGrayscale = [3][3]float64{{1.0 / 3, 1.0 / 3, 1.0 / 3}, {1.0 / 3, 1.0 / 3, 1.0 / 3}, {1.0 / 3, 1.0 / 3, 1.0 / 3}} // Mean of the channels, as ToPGM but rounded instead of truncated
+)
+
+// SplitRGB splits the PPM image into three PGM images holding its red, green and blue channels.
+func (ppm *PPM) SplitRGB() (red, green, blue *PGM) {
+	return ppm.planeToPGM(ppm.channelPlane(0)), ppm.planeToPGM(ppm.channelPlane(1)), ppm.planeToPGM(ppm.channelPlane(2))
+}
+
+// SplitYCbCr splits the PPM image into three PGM images holding its luma (Y) and chroma (Cb, Cr) components.
+// The components use the same maximum value as the image, the chroma being centered on max/2.
+func (ppm *PPM) SplitYCbCr() (luma, blueChroma, redChroma *PGM) {
+	planes := [3][][]uint8{}
+	for i := range planes {
+		planes[i] = make([][]uint8, ppm.height)
+	}
+
+	scale := 255 / float64(ppm.max) // YCbCr is defined on 0..255
+	for y := 0; y < ppm.height; y++ {
+		for i := range planes {
+			planes[i][y] = make([]uint8, ppm.width)
+		}
+		for x := 0; x < ppm.width; x++ {
+			pixel := ppm.data[y][x]
+			r, g, b := float64(pixel.R)*scale, float64(pixel.G)*scale, float64(pixel.B)*scale
+			planes[0][y][x] = unitToChannel((0.299*r+0.587*g+0.114*b)/255, ppm.max)
+			planes[1][y][x] = unitToChannel((128-0.168736*r-0.331264*g+0.5*b)/255, ppm.max)
+			planes[2][y][x] = unitToChannel((128+0.5*r-0.418688*g-0.081312*b)/255, ppm.max)
+		}
+	}
+	return ppm.planeToPGM(planes[0]), ppm.planeToPGM(planes[1]), ppm.planeToPGM(planes[2])
+}
+
+// MergeRGB builds a PPM image from three PGM images holding the red, green and blue channels.
+// The three images must have the same size and the same maximum value.
+func MergeRGB(red, green, blue *PGM) (*PPM, error) {
+	if err := checkSameChannels(red, green, blue); err != nil {
+		return nil, err
+	}
+
+	ppm := newPPMFromChannel(red)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			ppm.data[y][x] = Pixel{R: red.data[y][x], G: green.data[y][x], B: blue.data[y][x]}
+		}
+	}
+	return ppm, nil
+}
+
+// MergeYCbCr builds a PPM image from three PGM images holding the luma and chroma components, as returned by SplitYCbCr.
+func MergeYCbCr(luma, blueChroma, redChroma *PGM) (*PPM, error) {
+	if err := checkSameChannels(luma, blueChroma, redChroma); err != nil {
+		return nil, err
+	}
+
+	ppm := newPPMFromChannel(luma)
+	scale := 255 / float64(ppm.max) // YCbCr is defined on 0..255
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			color := YCbCr{
+				Y:  float64(luma.data[y][x]) * scale,
+				Cb: float64(blueChroma.data[y][x]) * scale,
+				Cr: float64(redChroma.data[y][x]) * scale,
+			}.Pixel()
+			// Bring the channels back to 0..max
+			ppm.data[y][x] = Pixel{
+				R: unitToChannel(float64(color.R)/255, ppm.max),
+				G: unitToChannel(float64(color.G)/255, ppm.max),
+				B: unitToChannel(float64(color.B)/255, ppm.max),
+			}
+		}
+	}
+	return ppm, nil
+}
+
+// ChannelMixer recomputes every pixel of the PPM image as a linear combination of its channels.
+// Each row of the matrix gives the weights of R, G and B in the red, green and blue outputs.
+func (ppm *PPM) ChannelMixer(matrix [3][3]float64) {
+	var withOffset [3][4]float64
+	for row := range matrix {
+		copy(withOffset[row][:3], matrix[row][:])
+	}
+	ppm.ChannelMixerOffset(withOffset)
+}
+
+// ChannelMixerOffset is like ChannelMixer with a fourth column added to each output channel,
+// expressed in channel values (0 to max).
+func (ppm *PPM) ChannelMixerOffset(matrix [3][4]float64) {
+	maxFloat := float64(ppm.max)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			pixel := ppm.data[y][x]
+			input := [3]float64{float64(pixel.R), float64(pixel.G), float64(pixel.B)}
+
+			var output [3]uint8
+			for channel, weights := range matrix {
+				value := weights[0]*input[0] + weights[1]*input[1] + weights[2]*input[2] + weights[3]
+				output[channel] = uint8(math.Round(math.Min(math.Max(value, 0), maxFloat))) // Clamp to 0..max
+			}
+			ppm.data[y][x] = Pixel{R: output[0], G: output[1], B: output[2]}
+		}
+	}
+}
+
+// planeToPGM wraps a plane of the PPM image into a PGM image with the same maximum value.
+func (ppm *PPM) planeToPGM(plane [][]uint8) *PGM {
+	return &PGM{
+		data:        plane,
+		width:       ppm.width,
+		height:      ppm.height,
+		magicNumber: "P2",
+		max:         ppm.max,
+	}
+}
+
+// newPPMFromChannel creates a PPM image with the size and maximum value of a channel image.
+func newPPMFromChannel(channel *PGM) *PPM {
+	ppm := &PPM{
+		data:        make([][]Pixel, channel.height),
+		width:       channel.width,
+		height:      channel.height,
+		magicNumber: "P3",
+		max:         channel.max,
+	}
+	for y := range ppm.data {
+		ppm.data[y] = make([]Pixel, channel.width)
+	}
+	return ppm
+}
+
+// checkSameChannels checks that three channel images have the same size and the same maximum value.
+func checkSameChannels(first, second, third *PGM) error {
+	for _, channel := range []*PGM{second, third} {
+		if channel.width != first.width || channel.height != first.height {
+			return fmt.Errorf("Channel size mismatch: %dx%d and %dx%d", first.width, first.height, channel.width, channel.height)
+		}
+		if channel.max != first.max {
+			return fmt.Errorf("Channel maximum value mismatch: %d and %d", first.max, channel.max)
+		}
+	}
+	return nil
+}
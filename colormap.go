@@ -0,0 +1,171 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ColorStop is a color placed at a position between 0 and 1 of a gradient.
+type ColorStop struct {
+	Position float64 // Position of the stop, from 0 (lowest value) to 1 (highest value)
+	Color    Pixel   // Color of the stop, with channels from 0 to 255
+}
+
+// Colormap is a gradient mapping values between 0 and 1 to colors, linearly interpolated between its stops.
+type Colormap struct {
+	Name  string      // Name of the colormap
+	Stops []ColorStop // Stops sorted by position
+}
+
+// Named colormaps, sampled from their reference definitions.
+var (
+	Viridis = &Colormap{Name: "viridis", Stops: evenStops(
+		Pixel{68, 1, 84}, Pixel{71, 44, 122}, Pixel{59, 82, 139}, Pixel{44, 114, 142}, Pixel{33, 145, 140},
+		Pixel{40, 174, 128}, Pixel{94, 201, 98}, Pixel{173, 220, 48}, Pixel{253, 231, 37})}
+	Inferno = &Colormap{Name: "inferno", Stops: evenStops(
+		Pixel{0, 0, 4}, Pixel{31, 12, 72}, Pixel{85, 15, 109}, Pixel{136, 34, 106}, Pixel{186, 54, 85},
+		Pixel{227, 89, 51}, Pixel{249, 140, 10}, Pixel{249, 201, 50}, Pixel{252, 255, 164})}
+	Magma = &Colormap{Name: "magma", Stops: evenStops(
+		Pixel{0, 0, 4}, Pixel{28, 16, 68}, Pixel{79, 18, 123}, Pixel{129, 37, 129}, Pixel{181, 54, 122},
+		Pixel{229, 80, 100}, Pixel{251, 135, 97}, Pixel{254, 194, 135}, Pixel{252, 253, 191})}
+	Plasma = &Colormap{Name: "plasma", Stops: evenStops(
+		Pixel{13, 8, 135}, Pixel{76, 2, 161}, Pixel{126, 3, 168}, Pixel{169, 35, 149}, Pixel{204, 71, 120},
+		Pixel{230, 108, 92}, Pixel{248, 149, 64}, Pixel{253, 197, 39}, Pixel{240, 249, 33})}
+	Turbo = &Colormap{Name: "turbo", Stops: evenStops(
+		Pixel{48, 18, 59}, Pixel{73, 88, 221}, Pixel{47, 158, 245}, Pixel{39, 215, 195}, Pixel{78, 249, 131}, Pixel{150, 250, 80},
+		Pixel{223, 220, 50}, Pixel{255, 163, 35}, Pixel{244, 92, 23}, Pixel{184, 32, 8}, Pixel{122, 4, 3})}
+	Jet = &Colormap{Name: "jet", Stops: []ColorStop{
+		{0, Pixel{0, 0, 128}}, {0.125, Pixel{0, 0, 255}}, {0.375, Pixel{0, 255, 255}},
+		{0.625, Pixel{255, 255, 0}}, {0.875, Pixel{255, 0, 0}}, {1, Pixel{128, 0, 0}}}}
+	Gray = &Colormap{Name: "gray", Stops: []ColorStop{{0, Pixel{0, 0, 0}}, {1, Pixel{255, 255, 255}}}}
+)
+
+// NewColormap creates a custom colormap from color stops. At least two stops are required, with positions between 0 and 1.
+func NewColormap(name string, stops []ColorStop) (*Colormap, error) {
+	if len(stops) < 2 {
+		return nil, fmt.Errorf("Invalid colormap: at least 2 color stops are required")
+	}
+	sorted := append([]ColorStop(nil), stops...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Position < sorted[j].Position })
+	for _, stop := range sorted {
+		if stop.Position < 0 || stop.Position > 1 {
+			return nil, fmt.Errorf("Invalid color stop position: %v (must be between 0 and 1)", stop.Position)
+		}
+	}
+	return &Colormap{Name: name, Stops: sorted}, nil
+}
+
+// NamedColormap returns the colormap with the given name: viridis, inferno, magma, plasma, turbo, jet or gray.
+func NamedColormap(name string) (*Colormap, error) {
+	for _, colormap := range []*Colormap{Viridis, Inferno, Magma, Plasma, Turbo, Jet, Gray} {
+		if strings.EqualFold(colormap.Name, name) {
+			return colormap, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown colormap: %s", name)
+}
+
+// At returns the color of the colormap at position t (clamped between 0 and 1).
+// A colormap without any stop is black everywhere.
+func (c *Colormap) At(t float64) Pixel {
+	stops := c.Stops
+	if len(stops) == 0 {
+		return Pixel{}
+	}
+	if t <= stops[0].Position || math.IsNaN(t) {
+		return stops[0].Color
+	}
+	if t >= stops[len(stops)-1].Position {
+		return stops[len(stops)-1].Color
+	}
+
+	// Find the two stops surrounding t and interpolate between them
+	next := sort.Search(len(stops), func(i int) bool { return stops[i].Position >= t })
+	before, after := stops[next-1], stops[next]
+	if after.Position == before.Position {
+		return after.Color
+	}
+	ratio := (t - before.Position) / (after.Position - before.Position)
+	return Pixel{
+		R: uint8(math.Round(float64(before.Color.R) + ratio*(float64(after.Color.R)-float64(before.Color.R)))),
+		G: uint8(math.Round(float64(before.Color.G) + ratio*(float64(after.Color.G)-float64(before.Color.G)))),
+		B: uint8(math.Round(float64(before.Color.B) + ratio*(float64(after.Color.B)-float64(before.Color.B)))),
+	}
+}
+
+// ToPPM converts the PGM image to a false-color PPM image, mapping 0 to max through the colormap.
+func (pgm *PGM) ToPPM(colormap *Colormap) *PPM {
+	return pgm.ToPPMRange(colormap, 0, pgm.max)
+}
+
+// ToPPMRange converts the PGM image to a false-color PPM image, mapping the values from low to high through the colormap.
+// Values outside of the range take the color of the nearest end.
+func (pgm *PGM) ToPPMRange(colormap *Colormap, low, high int) *PPM {
+	ppm := &PPM{
+		data:        make([][]Pixel, pgm.height),
+		width:       pgm.width,
+		height:      pgm.height,
+		magicNumber: "P3",
+		max:         255,
+	}
+
+	// Precompute the color of every possible gray level
+	lookup := make([]Pixel, pgm.max+1)
+	for level := range lookup {
+		if high == low {
+			lookup[level] = colormap.At(0)
+			if level > low {
+				lookup[level] = colormap.At(1)
+			}
+			continue
+		}
+		lookup[level] = colormap.At(float64(level-low) / float64(high-low))
+	}
+
+	for y := 0; y < pgm.height; y++ {
+		ppm.data[y] = make([]Pixel, pgm.width)
+		for x := 0; x < pgm.width; x++ {
+			ppm.data[y][x] = lookup[min(int(pgm.data[y][x]), pgm.max)]
+		}
+	}
+	return ppm
+}
+
+// DrawColorbar draws the colormap as a legend inside the rectangle starting at topLeft.
+// A vertical colorbar has its lowest value at the bottom, a horizontal one at the left; the outline is drawn with borderColor.
+func (ppm *PPM) DrawColorbar(colormap *Colormap, topLeft Point, width, height int, vertical bool, borderColor Pixel) error {
+	if width < 1 || height < 1 {
+		return fmt.Errorf("Invalid colorbar size: %dx%d", width, height)
+	}
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			x, y := topLeft.X+col, topLeft.Y+row
+			if x < 0 || x >= ppm.width || y < 0 || y >= ppm.height { // Skip the pixels outside of the image
+				continue
+			}
+
+			var t float64
+			if vertical && height > 1 {
+				t = float64(height-1-row) / float64(height-1)
+			} else if !vertical && width > 1 {
+				t = float64(col) / float64(width-1)
+			}
+			color := colormap.At(t)
+			// Express the color in the range of the image
+			ppm.data[y][x] = Pixel{R: unitToChannel(float64(color.R)/255, ppm.max), G: unitToChannel(float64(color.G)/255, ppm.max), B: unitToChannel(float64(color.B)/255, ppm.max)}
+		}
+	}
+	ppm.DrawRectangle(topLeft, width-1, height-1, borderColor)
+	return nil
+}
+
+// evenStops spreads colors evenly between 0 and 1.
+func evenStops(colors ...Pixel) []ColorStop {
+	stops := make([]ColorStop, len(colors))
+	for i, color := range colors {
+		stops[i] = ColorStop{Position: float64(i) / float64(len(colors)-1), Color: color}
+	}
+	return stops
+}
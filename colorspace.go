@@ -0,0 +1,394 @@
+package Netpbm
+
+import "math"
+
+// HSV represents a color by its hue (0 to 360 degrees), saturation (0 to 1) and value (0 to 1).
+type HSV struct {
+	H, S, V float64
+}
+
+// HSL represents a color by its hue (0 to 360 degrees), saturation (0 to 1) and lightness (0 to 1).
+type HSL struct {
+	H, S, L float64
+}
+
+// YCbCr represents a color by its luma and chroma components (full range JPEG convention, 0 to 255).
+type YCbCr struct {
+	Y, Cb, Cr float64
+}
+
+// XYZ represents a color in the CIE 1931 XYZ space (D65 white point, Y from 0 to 1).
+type XYZ struct {
+	X, Y, Z float64
+}
+
+// Lab represents a color in the CIE L*a*b* space (D65 white point, L from 0 to 100).
+type Lab struct {
+	L, A, B float64
+}
+
+// LCh represents a color in the cylindrical form of CIE L*a*b*: lightness, chroma and hue (0 to 360 degrees).
+type LCh struct {
+	L, C, H float64
+}
+
+// D65 reference white used by the XYZ and Lab conversions.
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+// HSV converts the pixel (channels from 0 to 255) to HSV.
+func (p Pixel) HSV() HSV {
+	return rgbToHSV(float64(p.R)/255, float64(p.G)/255, float64(p.B)/255)
+}
+
+// Pixel converts the HSV color to a pixel with channels from 0 to 255.
+func (c HSV) Pixel() Pixel {
+	return pixelFromUnit(hsvToRGB(c))
+}
+
+// HSL converts the pixel (channels from 0 to 255) to HSL.
+func (p Pixel) HSL() HSL {
+	return rgbToHSL(float64(p.R)/255, float64(p.G)/255, float64(p.B)/255)
+}
+
+// Pixel converts the HSL color to a pixel with channels from 0 to 255.
+func (c HSL) Pixel() Pixel {
+	return pixelFromUnit(hslToRGB(c))
+}
+
+// YCbCr converts the pixel (channels from 0 to 255) to YCbCr.
+func (p Pixel) YCbCr() YCbCr {
+	r, g, b := float64(p.R), float64(p.G), float64(p.B)
+	return YCbCr{
+		Y:  0.299*r + 0.587*g + 0.114*b,
+		Cb: 128 - 0.168736*r - 0.331264*g + 0.5*b,
+		Cr: 128 + 0.5*r - 0.418688*g - 0.081312*b,
+	}
+}
+
+// Pixel converts the YCbCr color to a pixel with channels from 0 to 255.
+func (c YCbCr) Pixel() Pixel {
+	r := c.Y + 1.402*(c.Cr-128)
+	g := c.Y - 0.344136*(c.Cb-128) - 0.714136*(c.Cr-128)
+	b := c.Y + 1.772*(c.Cb-128)
+	return pixelFromUnit(r/255, g/255, b/255)
+}
+
+// XYZ converts the pixel (sRGB channels from 0 to 255) to CIE XYZ.
+func (p Pixel) XYZ() XYZ {
+	return rgbToXYZ(float64(p.R)/255, float64(p.G)/255, float64(p.B)/255)
+}
+
+// Pixel converts the XYZ color to an sRGB pixel with channels from 0 to 255.
+func (c XYZ) Pixel() Pixel {
+	return pixelFromUnit(xyzToRGB(c))
+}
+
+// Lab converts the XYZ color to CIE L*a*b*.
+func (c XYZ) Lab() Lab {
+	fx, fy, fz := labF(c.X/whiteX), labF(c.Y/whiteY), labF(c.Z/whiteZ)
+	return Lab{L: 116*fy - 16, A: 500 * (fx - fy), B: 200 * (fy - fz)}
+}
+
+// XYZ converts the Lab color to CIE XYZ.
+func (c Lab) XYZ() XYZ {
+	fy := (c.L + 16) / 116
+	fx := fy + c.A/500
+	fz := fy - c.B/200
+	return XYZ{X: whiteX * labFInverse(fx), Y: whiteY * labFInverse(fy), Z: whiteZ * labFInverse(fz)}
+}
+
+// Lab converts the pixel (sRGB channels from 0 to 255) to CIE L*a*b*.
+func (p Pixel) Lab() Lab {
+	return p.XYZ().Lab()
+}
+
+// Pixel converts the Lab color to an sRGB pixel with channels from 0 to 255.
+func (c Lab) Pixel() Pixel {
+	return c.XYZ().Pixel()
+}
+
+// LCh converts the Lab color to its cylindrical form.
+func (c Lab) LCh() LCh {
+	return LCh{L: c.L, C: math.Hypot(c.A, c.B), H: normalizeHue(math.Atan2(c.B, c.A) * 180 / math.Pi)}
+}
+
+// Lab converts the LCh color back to CIE L*a*b*.
+func (c LCh) Lab() Lab {
+	angle := c.H * math.Pi / 180
+	return Lab{L: c.L, A: c.C * math.Cos(angle), B: c.C * math.Sin(angle)}
+}
+
+// LCh converts the pixel (sRGB channels from 0 to 255) to LCh.
+func (p Pixel) LCh() LCh {
+	return p.Lab().LCh()
+}
+
+// Pixel converts the LCh color to an sRGB pixel with channels from 0 to 255.
+func (c LCh) Pixel() Pixel {
+	return c.Lab().Pixel()
+}
+
+// DeltaE returns the CIEDE2000 color difference between two pixels.
+func (p Pixel) DeltaE(other Pixel) float64 {
+	return DeltaE2000(p.Lab(), other.Lab())
+}
+
+// DeltaE2000 returns the CIEDE2000 color difference between two Lab colors.
+// A difference below 1 is generally not perceptible by the human eye.
+func DeltaE2000(first, second Lab) float64 {
+	const deg = math.Pi / 180
+
+	// Chroma compensation of the a* axis
+	meanC := (math.Hypot(first.A, first.B) + math.Hypot(second.A, second.B)) / 2
+	meanC7 := math.Pow(meanC, 7)
+	g := 0.5 * (1 - math.Sqrt(meanC7/(meanC7+math.Pow(25, 7))))
+	a1, a2 := first.A*(1+g), second.A*(1+g)
+
+	c1, c2 := math.Hypot(a1, first.B), math.Hypot(a2, second.B)
+	h1, h2 := 0.0, 0.0
+	if c1 != 0 {
+		h1 = normalizeHue(math.Atan2(first.B, a1) / deg)
+	}
+	if c2 != 0 {
+		h2 = normalizeHue(math.Atan2(second.B, a2) / deg)
+	}
+
+	// Differences in lightness, chroma and hue
+	deltaL := second.L - first.L
+	deltaC := c2 - c1
+	deltaH := 0.0
+	if c1*c2 != 0 {
+		deltaH = h2 - h1
+		if deltaH > 180 {
+			deltaH -= 360
+		} else if deltaH < -180 {
+			deltaH += 360
+		}
+	}
+	deltaBigH := 2 * math.Sqrt(c1*c2) * math.Sin(deltaH/2*deg)
+
+	// Means used by the weighting functions
+	meanL := (first.L + second.L) / 2
+	meanCPrime := (c1 + c2) / 2
+	meanH := h1 + h2
+	if c1*c2 != 0 {
+		if math.Abs(h1-h2) <= 180 {
+			meanH = (h1 + h2) / 2
+		} else if h1+h2 < 360 {
+			meanH = (h1 + h2 + 360) / 2
+		} else {
+			meanH = (h1 + h2 - 360) / 2
+		}
+	}
+
+	t := 1 - 0.17*math.Cos((meanH-30)*deg) + 0.24*math.Cos(2*meanH*deg) + 0.32*math.Cos((3*meanH+6)*deg) - 0.20*math.Cos((4*meanH-63)*deg)
+	deltaTheta := 30 * math.Exp(-math.Pow((meanH-275)/25, 2))
+	meanC7Prime := math.Pow(meanCPrime, 7)
+	rc := 2 * math.Sqrt(meanC7Prime/(meanC7Prime+math.Pow(25, 7)))
+	sl := 1 + 0.015*(meanL-50)*(meanL-50)/math.Sqrt(20+(meanL-50)*(meanL-50))
+	sc := 1 + 0.045*meanCPrime
+	sh := 1 + 0.015*meanCPrime*t
+	rt := -math.Sin(2*deltaTheta*deg) * rc
+
+	termL, termC, termH := deltaL/sl, deltaC/sc, deltaBigH/sh
+	return math.Sqrt(termL*termL + termC*termC + termH*termH + rt*termC*termH)
+}
+
+// RotateHue rotates the hue of every pixel of the PPM image by the given angle in degrees.
+func (ppm *PPM) RotateHue(degrees float64) {
+	ppm.mapUnitColors(func(r, g, b float64) (float64, float64, float64) {
+		color := rgbToHSL(r, g, b)
+		color.H = normalizeHue(color.H + degrees)
+		return hslToRGB(color)
+	})
+}
+
+// AdjustSaturation multiplies the saturation of every pixel of the PPM image by factor (0 gives a gray image).
+func (ppm *PPM) AdjustSaturation(factor float64) {
+	ppm.mapUnitColors(func(r, g, b float64) (float64, float64, float64) {
+		color := rgbToHSL(r, g, b)
+		color.S = math.Min(math.Max(color.S*factor, 0), 1)
+		return hslToRGB(color)
+	})
+}
+
+// AdjustLightness adds delta (on the 0 to 100 scale of CIE L*) to the lightness of every pixel of the PPM image,
+// leaving the a* and b* components, and so the perceived hue and chroma, unchanged.
+func (ppm *PPM) AdjustLightness(delta float64) {
+	ppm.mapUnitColors(func(r, g, b float64) (float64, float64, float64) {
+		color := rgbToXYZ(r, g, b).Lab()
+		color.L = math.Min(math.Max(color.L+delta, 0), 100)
+		return xyzToRGB(color.XYZ())
+	})
+}
+
+// mapUnitColors replaces every pixel of the PPM image through a function working on channels from 0 to 1.
+func (ppm *PPM) mapUnitColors(transform func(r, g, b float64) (float64, float64, float64)) {
+	maxFloat := float64(ppm.max)
+	cache := make(map[Pixel]Pixel) // Conversions are costly and colors are often repeated
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			pixel := ppm.data[y][x]
+			result, ok := cache[pixel]
+			if !ok {
+				r, g, b := transform(float64(pixel.R)/maxFloat, float64(pixel.G)/maxFloat, float64(pixel.B)/maxFloat)
+				result = Pixel{R: unitToChannel(r, ppm.max), G: unitToChannel(g, ppm.max), B: unitToChannel(b, ppm.max)}
+				cache[pixel] = result
+			}
+			ppm.data[y][x] = result
+		}
+	}
+}
+
+// unitToChannel converts a value from 0 to 1 to a channel value from 0 to maxValue, clamping it.
+func unitToChannel(value float64, maxValue int) uint8 {
+	return uint8(math.Round(math.Min(math.Max(value, 0), 1) * float64(maxValue)))
+}
+
+// pixelFromUnit converts channels from 0 to 1 to a pixel with channels from 0 to 255.
+func pixelFromUnit(r, g, b float64) Pixel {
+	return Pixel{R: unitToChannel(r, 255), G: unitToChannel(g, 255), B: unitToChannel(b, 255)}
+}
+
+// normalizeHue brings an angle in degrees back into [0, 360).
+func normalizeHue(hue float64) float64 {
+	hue = math.Mod(hue, 360)
+	if hue < 0 {
+		hue += 360
+	}
+	return hue
+}
+
+// hueOf returns the hue in degrees of channels from 0 to 1, given their maximum and chroma.
+func hueOf(r, g, b, maximum, chroma float64) float64 {
+	if chroma == 0 { // Gray: the hue is undefined, use 0
+		return 0
+	}
+	var hue float64
+	switch maximum {
+	case r:
+		hue = math.Mod((g-b)/chroma, 6)
+	case g:
+		hue = (b-r)/chroma + 2
+	default:
+		hue = (r-g)/chroma + 4
+	}
+	return normalizeHue(hue * 60)
+}
+
+// rgbToHSV converts channels from 0 to 1 to HSV.
+func rgbToHSV(r, g, b float64) HSV {
+	maximum := math.Max(r, math.Max(g, b))
+	minimum := math.Min(r, math.Min(g, b))
+	chroma := maximum - minimum
+
+	saturation := 0.0
+	if maximum > 0 {
+		saturation = chroma / maximum
+	}
+	return HSV{H: hueOf(r, g, b, maximum, chroma), S: saturation, V: maximum}
+}
+
+// hsvToRGB converts an HSV color to channels from 0 to 1.
+func hsvToRGB(c HSV) (float64, float64, float64) {
+	chroma := c.V * c.S
+	return hueToRGB(normalizeHue(c.H), chroma, c.V-chroma)
+}
+
+// rgbToHSL converts channels from 0 to 1 to HSL.
+func rgbToHSL(r, g, b float64) HSL {
+	maximum := math.Max(r, math.Max(g, b))
+	minimum := math.Min(r, math.Min(g, b))
+	chroma := maximum - minimum
+	lightness := (maximum + minimum) / 2
+
+	saturation := 0.0
+	if chroma > 0 {
+		saturation = chroma / (1 - math.Abs(2*lightness-1))
+	}
+	return HSL{H: hueOf(r, g, b, maximum, chroma), S: saturation, L: lightness}
+}
+
+// hslToRGB converts an HSL color to channels from 0 to 1.
+func hslToRGB(c HSL) (float64, float64, float64) {
+	chroma := (1 - math.Abs(2*c.L-1)) * c.S
+	return hueToRGB(normalizeHue(c.H), chroma, c.L-chroma/2)
+}
+
+// hueToRGB builds channels from a hue, a chroma and the value added to every channel.
+func hueToRGB(hue, chroma, offset float64) (float64, float64, float64) {
+	sector := hue / 60
+	secondary := chroma * (1 - math.Abs(math.Mod(sector, 2)-1))
+
+	var r, g, b float64
+	switch int(sector) {
+	case 0:
+		r, g, b = chroma, secondary, 0
+	case 1:
+		r, g, b = secondary, chroma, 0
+	case 2:
+		r, g, b = 0, chroma, secondary
+	case 3:
+		r, g, b = 0, secondary, chroma
+	case 4:
+		r, g, b = secondary, 0, chroma
+	default:
+		r, g, b = chroma, 0, secondary
+	}
+	return r + offset, g + offset, b + offset
+}
+
+// srgbToLinear removes the sRGB transfer curve from a channel from 0 to 1.
+func srgbToLinear(value float64) float64 {
+	if value <= 0.04045 {
+		return value / 12.92
+	}
+	return math.Pow((value+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB applies the sRGB transfer curve to a linear channel from 0 to 1.
+func linearToSRGB(value float64) float64 {
+	if value <= 0.0031308 {
+		return value * 12.92
+	}
+	return 1.055*math.Pow(value, 1/2.4) - 0.055
+}
+
+// rgbToXYZ converts sRGB channels from 0 to 1 to CIE XYZ.
+func rgbToXYZ(r, g, b float64) XYZ {
+	r, g, b = srgbToLinear(r), srgbToLinear(g), srgbToLinear(b)
+	return XYZ{
+		X: 0.4124564*r + 0.3575761*g + 0.1804375*b,
+		Y: 0.2126729*r + 0.7151522*g + 0.0721750*b,
+		Z: 0.0193339*r + 0.1191920*g + 0.9503041*b,
+	}
+}
+
+// xyzToRGB converts a CIE XYZ color to sRGB channels from 0 to 1.
+func xyzToRGB(c XYZ) (float64, float64, float64) {
+	r := 3.2404542*c.X - 1.5371385*c.Y - 0.4985314*c.Z
+	g := -0.9692660*c.X + 1.8760108*c.Y + 0.0415560*c.Z
+	b := 0.0556434*c.X - 0.2040259*c.Y + 1.0572252*c.Z
+	return linearToSRGB(math.Max(r, 0)), linearToSRGB(math.Max(g, 0)), linearToSRGB(math.Max(b, 0))
+}
+
+// labF is the nonlinear compression used by the XYZ to Lab conversion.
+func labF(t float64) float64 {
+	const epsilon = 216.0 / 24389
+	if t > epsilon {
+		return math.Cbrt(t)
+	}
+	return (24389.0/27*t + 16) / 116
+}
+
+// labFInverse is the inverse of labF.
+func labFInverse(t float64) float64 {
+	if t*t*t > 216.0/24389 {
+		return t * t * t
+	}
+	return (116*t - 16) * 27 / 24389
+}
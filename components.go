@@ -0,0 +1,270 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Connectivity is the neighborhood used to decide whether two pixels touch.
+type Connectivity int
+
+const (
+	Connectivity4 Connectivity = 4 // Pixels touch through their sides
+	Connectivity8 Connectivity = 8 // Pixels touch through their sides or corners
+)
+
+// Rectangle is an axis-aligned rectangle given by its top left corner and its size.
+type Rectangle struct {
+	TopLeft       Point // Top left corner
+	Width, Height int   // Size of the rectangle
+}
+
+// Component describes a connected set of set pixels of a PBM image.
+type Component struct {
+	Label                int       // Label of the component in Labeling.Labels (starting at 1)
+	Area                 int       // Number of pixels
+	Bounds               Rectangle // Bounding box
+	CentroidX, CentroidY float64   // Mean position of the pixels
+	Perimeter            int       // Number of pixel sides between the component and the rest of the image
+	Holes                int       // Number of background regions enclosed by the component
+}
+
+// Labeling is the result of a connected-component labeling.
+type Labeling struct {
+	Labels        [][]int      // Label of each pixel, 0 for the background
+	Components    []Component  // Components, Components[i] having the label i+1
+	width, height int          // Size of the labelled image
+	connectivity  Connectivity // Connectivity used to build the components
+}
+
+// neighborOffsets returns the offsets of the neighbors of a pixel for the given connectivity.
+func neighborOffsets(connectivity Connectivity) []Point {
+	if connectivity == Connectivity4 {
+		return []Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	}
+	return []Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {-1, -1}, {1, -1}, {-1, 1}}
+}
+
+// Label finds the connected components of the set pixels of the PBM image and measures them.
+func (pbm *PBM) Label(connectivity Connectivity) (*Labeling, error) {
+	if connectivity != Connectivity4 && connectivity != Connectivity8 {
+		return nil, fmt.Errorf("Unsupported connectivity: %d (must be 4 or 8)", connectivity)
+	}
+
+	labeling := &Labeling{
+		Labels:       make([][]int, pbm.height),
+		width:        pbm.width,
+		height:       pbm.height,
+		connectivity: connectivity,
+	}
+	for y := range labeling.Labels {
+		labeling.Labels[y] = make([]int, pbm.width)
+	}
+
+	offsets := neighborOffsets(connectivity)
+	var stack []Point
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if !pbm.data[y][x] || labeling.Labels[y][x] != 0 { // Background or already labelled
+				continue
+			}
+
+			// Flood the new component from this pixel
+			label := len(labeling.Components) + 1
+			labeling.Labels[y][x] = label
+			stack = append(stack[:0], Point{x, y})
+			for len(stack) > 0 {
+				current := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				for _, offset := range offsets {
+					nx, ny := current.X+offset.X, current.Y+offset.Y
+					if nx >= 0 && nx < pbm.width && ny >= 0 && ny < pbm.height && pbm.data[ny][nx] && labeling.Labels[ny][nx] == 0 {
+						labeling.Labels[ny][nx] = label
+						stack = append(stack, Point{nx, ny})
+					}
+				}
+			}
+			labeling.Components = append(labeling.Components, Component{Label: label})
+		}
+	}
+
+	labeling.measure()
+	return labeling, nil
+}
+
+// Count returns the number of components.
+func (l *Labeling) Count() int {
+	return len(l.Components)
+}
+
+// Filter removes the components whose area is below minArea or above maxArea (0 means no upper limit),
+// then renumbers the remaining ones.
+func (l *Labeling) Filter(minArea, maxArea int) {
+	mapping := make([]int, len(l.Components)+1) // New label of each old label, 0 if removed
+	var kept []Component
+	for _, component := range l.Components {
+		if component.Area < minArea || (maxArea > 0 && component.Area > maxArea) {
+			continue
+		}
+		mapping[component.Label] = len(kept) + 1
+		component.Label = len(kept) + 1
+		kept = append(kept, component)
+	}
+
+	for y := 0; y < l.height; y++ {
+		for x := 0; x < l.width; x++ {
+			l.Labels[y][x] = mapping[l.Labels[y][x]]
+		}
+	}
+	l.Components = kept
+}
+
+// Mask returns a PBM image where the pixels of the remaining components are set.
+func (l *Labeling) Mask() *PBM {
+	pbm := &PBM{
+		data:        newMask(l.width, l.height),
+		width:       l.width,
+		height:      l.height,
+		magicNumber: "P1",
+	}
+	for y := 0; y < l.height; y++ {
+		for x := 0; x < l.width; x++ {
+			pbm.data[y][x] = l.Labels[y][x] != 0
+		}
+	}
+	return pbm
+}
+
+// Render draws each component in a distinct random color on a black background.
+// The colors depend only on the seed, so that renderings can be reproduced.
+func (l *Labeling) Render(seed int64) *PPM {
+	colors := labelColors(len(l.Components), seed)
+
+	ppm := &PPM{
+		data:        make([][]Pixel, l.height),
+		width:       l.width,
+		height:      l.height,
+		magicNumber: "P3",
+		max:         255,
+	}
+	for y := 0; y < l.height; y++ {
+		ppm.data[y] = make([]Pixel, l.width)
+		for x := 0; x < l.width; x++ {
+			ppm.data[y][x] = colors[l.Labels[y][x]]
+		}
+	}
+	return ppm
+}
+
+// labelColors returns count+1 distinct random colors, the first one being black for the background.
+func labelColors(count int, seed int64) []Pixel {
+	random := rand.New(rand.NewSource(seed))
+	colors := make([]Pixel, count+1)
+	hue := random.Float64() * 360
+	for i := 1; i < len(colors); i++ {
+		// Step the hue by the golden angle so that neighbors in label order stay far apart
+		hue = normalizeHue(hue + 137.50776405)
+		colors[i] = HSV{H: hue, S: 0.55 + 0.45*random.Float64(), V: 0.7 + 0.3*random.Float64()}.Pixel()
+	}
+	return colors
+}
+
+// FilterComponents removes from the PBM image the components whose area is below minArea or above maxArea
+// (0 means no upper limit).
+func (pbm *PBM) FilterComponents(connectivity Connectivity, minArea, maxArea int) error {
+	labeling, err := pbm.Label(connectivity)
+	if err != nil {
+		return err
+	}
+	labeling.Filter(minArea, maxArea)
+	pbm.data = labeling.Mask().data
+	return nil
+}
+
+// measure computes the statistics of every component.
+func (l *Labeling) measure() {
+	type accumulator struct {
+		minX, minY, maxX, maxY int
+		sumX, sumY             float64
+	}
+	stats := make([]accumulator, len(l.Components))
+	for i := range stats {
+		stats[i] = accumulator{minX: math.MaxInt, minY: math.MaxInt, maxX: -1, maxY: -1}
+	}
+
+	for y := 0; y < l.height; y++ {
+		for x := 0; x < l.width; x++ {
+			label := l.Labels[y][x]
+			if label == 0 {
+				continue
+			}
+			component, stat := &l.Components[label-1], &stats[label-1]
+			component.Area++
+			stat.sumX += float64(x)
+			stat.sumY += float64(y)
+			stat.minX, stat.maxX = min(stat.minX, x), max(stat.maxX, x)
+			stat.minY, stat.maxY = min(stat.minY, y), max(stat.maxY, y)
+
+			// Count the sides facing another label or the outside of the image
+			for _, offset := range neighborOffsets(Connectivity4) {
+				nx, ny := x+offset.X, y+offset.Y
+				if nx < 0 || nx >= l.width || ny < 0 || ny >= l.height || l.Labels[ny][nx] != label {
+					component.Perimeter++
+				}
+			}
+		}
+	}
+
+	for i := range l.Components {
+		component, stat := &l.Components[i], stats[i]
+		component.Bounds = Rectangle{TopLeft: Point{stat.minX, stat.minY}, Width: stat.maxX - stat.minX + 1, Height: stat.maxY - stat.minY + 1}
+		component.CentroidX = stat.sumX / float64(component.Area)
+		component.CentroidY = stat.sumY / float64(component.Area)
+		component.Holes = l.countHoles(*component)
+	}
+}
+
+// countHoles counts the background regions enclosed by a component. The background uses the connectivity
+// complementary to the one of the labeling so that diagonal gaps do not open a hole.
+func (l *Labeling) countHoles(component Component) int {
+	// Work on the bounding box extended by one pixel, whose border is always outside of the component
+	left, top := component.Bounds.TopLeft.X-1, component.Bounds.TopLeft.Y-1
+	width, height := component.Bounds.Width+2, component.Bounds.Height+2
+	inside := func(x, y int) bool { // Whether (x, y) of the extended box belongs to the component
+		ix, iy := left+x, top+y
+		return ix >= 0 && ix < l.width && iy >= 0 && iy < l.height && l.Labels[iy][ix] == component.Label
+	}
+
+	backgroundConnectivity := Connectivity8
+	if l.connectivity == Connectivity8 {
+		backgroundConnectivity = Connectivity4
+	}
+	offsets := neighborOffsets(backgroundConnectivity)
+
+	visited := newMask(width, height)
+	regions := 0
+	var stack []Point
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if visited[y][x] || inside(x, y) {
+				continue
+			}
+			regions++
+			visited[y][x] = true
+			stack = append(stack[:0], Point{x, y})
+			for len(stack) > 0 {
+				current := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				for _, offset := range offsets {
+					nx, ny := current.X+offset.X, current.Y+offset.Y
+					if nx >= 0 && nx < width && ny >= 0 && ny < height && !visited[ny][nx] && !inside(nx, ny) {
+						visited[ny][nx] = true
+						stack = append(stack, Point{nx, ny})
+					}
+				}
+			}
+		}
+	}
+	return regions - 1 // The region touching the border of the box is the outside
+}
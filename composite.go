@@ -0,0 +1,187 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// CompositeOperator selects the Porter–Duff operator used to combine a source image with a destination image.
+type CompositeOperator int
+
+const (
+	CompositeOver CompositeOperator = iota // Source over destination
+	CompositeIn                            // Source where the destination is opaque
+	CompositeOut                           // Source where the destination is transparent
+	CompositeAtop                          // Source over destination, only where the destination is opaque
+	CompositeXor                           // Source and destination where the other one is transparent
+)
+
+// AlphaMask is implemented by the images usable as alpha masks: PGM alpha masks and PBM stencils only.
+type AlphaMask interface {
+	Size() (int, int)
+	alphaMask() // Keeps other types, such as PPM, from satisfying the interface
+}
+
+func (pbm *PBM) alphaMask() {}
+func (pgm *PGM) alphaMask() {}
+
+// CompositeOptions controls how a source image is pasted onto a destination image.
+type CompositeOptions struct {
+	Offset          Point             // Position of the top left corner of the source in the destination
+	Mask            AlphaMask         // Alpha of the source: a PGM alpha mask or a PBM stencil (set pixels opaque), nil for opaque
+	DestinationMask AlphaMask         // Alpha of the destination, as Mask; it receives the alpha of the result
+	Operator        CompositeOperator // Porter–Duff operator
+	Background      Pixel             // Color showing through transparent results when there is no DestinationMask
+}
+
+// Composite pastes the source image onto the PPM image. Only the area covered by the source is changed, and the
+// parts of the source falling outside of the image are ignored. Without DestinationMask the image is opaque and
+// the result is flattened onto the background color; with it, the colors of the result are stored unpremultiplied
+// and its alpha is written back into the mask. The source values are rescaled to the maximum value of the image.
+// The source may share its pixels with the image, for instance to composite an image onto itself.
+func (ppm *PPM) Composite(source *PPM, options CompositeOptions) error {
+	sourceAlpha, destinationAlpha, err := compositeAlphas(source.width, source.height, ppm.width, ppm.height, options)
+	if err != nil {
+		return err
+	}
+	sourceData := clonePlane(source.data) // The loop below writes pixels that may still have to be read
+	sourceScale := float64(ppm.max) / float64(source.max)
+	background := pixelToFloats(options.Background)
+	compositeArea(source.width, source.height, ppm.width, ppm.height, options, sourceAlpha, destinationAlpha,
+		func(sx, sy, dx, dy int, alphaS, alphaD float64) float64 {
+			sourceValues := pixelToFloats(sourceData[sy][sx])
+			destinationValues := pixelToFloats(ppm.data[dy][dx])
+			var result [3]uint8
+			var alpha float64
+			for channel := range result {
+				var value float64
+				value, alpha = porterDuff(options.Operator, sourceValues[channel]*sourceScale, alphaS, destinationValues[channel], alphaD, background[channel], options.DestinationMask != nil)
+				result[channel] = uint8(math.Round(math.Max(0, math.Min(value, float64(ppm.max)))))
+			}
+			ppm.data[dy][dx] = Pixel{result[0], result[1], result[2]}
+			return alpha
+		})
+	return nil
+}
+
+// Composite pastes the source image onto the PGM image, as PPM.Composite does.
+func (pgm *PGM) Composite(source *PGM, options CompositeOptions) error {
+	sourceAlpha, destinationAlpha, err := compositeAlphas(source.width, source.height, pgm.width, pgm.height, options)
+	if err != nil {
+		return err
+	}
+	sourceData := clonePlane(source.data) // The loop below writes pixels that may still have to be read
+	sourceScale := float64(pgm.max) / float64(source.max)
+	background := float64(options.Background.R) // Gray background: the red channel is used
+	compositeArea(source.width, source.height, pgm.width, pgm.height, options, sourceAlpha, destinationAlpha,
+		func(sx, sy, dx, dy int, alphaS, alphaD float64) float64 {
+			value, alpha := porterDuff(options.Operator, float64(sourceData[sy][sx])*sourceScale, alphaS, float64(pgm.data[dy][dx]), alphaD, background, options.DestinationMask != nil)
+			pgm.data[dy][dx] = uint8(math.Round(math.Max(0, math.Min(value, float64(pgm.max)))))
+			return alpha
+		})
+	return nil
+}
+
+// clonePlane returns a copy of a plane that shares no memory with it.
+func clonePlane[T any](data [][]T) [][]T {
+	clone := make([][]T, len(data))
+	for y, row := range data {
+		clone[y] = append([]T(nil), row...)
+	}
+	return clone
+}
+
+// porterDuff combines a source and a destination value with their alphas. When keepAlpha is set, it returns the
+// unpremultiplied value and the alpha of the result; otherwise the result is flattened onto the background.
+func porterDuff(operator CompositeOperator, source, alphaS, destination, alphaD, background float64, keepAlpha bool) (float64, float64) {
+	var fa, fb float64 // Fractions of the source and of the destination kept by the operator
+	switch operator {
+	case CompositeOver:
+		fa, fb = 1, 1-alphaS
+	case CompositeIn:
+		fa, fb = alphaD, 0
+	case CompositeOut:
+		fa, fb = 1-alphaD, 0
+	case CompositeAtop:
+		fa, fb = alphaD, 1-alphaS
+	case CompositeXor:
+		fa, fb = 1-alphaD, 1-alphaS
+	}
+	alpha := alphaS*fa + alphaD*fb
+	premultiplied := alphaS*fa*source + alphaD*fb*destination
+	if !keepAlpha {
+		return premultiplied + (1-alpha)*background, alpha
+	}
+	if alpha == 0 {
+		return background, 0
+	}
+	return premultiplied / alpha, alpha
+}
+
+// compositeAlphas checks the options and returns the alpha planes of the source and of the destination,
+// nil standing for an opaque image.
+func compositeAlphas(sourceWidth, sourceHeight, width, height int, options CompositeOptions) ([][]float64, [][]float64, error) {
+	if options.Operator < CompositeOver || options.Operator > CompositeXor {
+		return nil, nil, fmt.Errorf("Unsupported composite operator: %d", options.Operator)
+	}
+	sourceAlpha, err := alphaPlane(options.Mask, sourceWidth, sourceHeight)
+	if err != nil {
+		return nil, nil, err
+	}
+	destinationAlpha, err := alphaPlane(options.DestinationMask, width, height)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sourceAlpha, destinationAlpha, nil
+}
+
+// alphaPlane returns the alphas, between 0 and 1, given by a PGM alpha mask or a PBM stencil of the given size.
+func alphaPlane(mask AlphaMask, width, height int) ([][]float64, error) {
+	if mask == nil {
+		return nil, nil
+	}
+	if maskWidth, maskHeight := mask.Size(); maskWidth != width || maskHeight != height {
+		return nil, fmt.Errorf("The %dx%d mask does not match the %dx%d image", maskWidth, maskHeight, width, height)
+	}
+	switch mask := mask.(type) {
+	case *PGM:
+		plane := uint8PlaneToFloat(mask.data)
+		for _, row := range plane {
+			for x := range row {
+				row[x] /= float64(mask.max)
+			}
+		}
+		return plane, nil
+	case *PBM:
+		return mask.floatPlane(), nil
+	default:
+		return nil, fmt.Errorf("Unsupported mask type: %T", mask)
+	}
+}
+
+// compositeArea calls combine for each pixel of the source falling inside the destination, with the source and
+// destination alphas, and stores the alpha it returns into the destination mask when there is one.
+func compositeArea(sourceWidth, sourceHeight, width, height int, options CompositeOptions, sourceAlpha, destinationAlpha [][]float64,
+	combine func(sx, sy, dx, dy int, alphaS, alphaD float64) float64) {
+	for sy := max(0, -options.Offset.Y); sy < min(sourceHeight, height-options.Offset.Y); sy++ {
+		dy := sy + options.Offset.Y
+		for sx := max(0, -options.Offset.X); sx < min(sourceWidth, width-options.Offset.X); sx++ {
+			dx := sx + options.Offset.X
+			alphaS, alphaD := 1.0, 1.0
+			if sourceAlpha != nil {
+				alphaS = sourceAlpha[sy][sx]
+			}
+			if destinationAlpha != nil {
+				alphaD = destinationAlpha[dy][dx]
+			}
+			alpha := combine(sx, sy, dx, dy, alphaS, alphaD)
+
+			switch mask := options.DestinationMask.(type) {
+			case *PGM:
+				mask.data[dy][dx] = uint8(math.Round(alpha * float64(mask.max)))
+			case *PBM:
+				mask.data[dy][dx] = alpha >= 0.5
+			}
+		}
+	}
+}
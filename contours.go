@@ -0,0 +1,473 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Contour is the border of a connected component of set pixels, or of a hole inside one.
+type Contour struct {
+	Points []Point // Border pixels, in order along the border
+	Hole   bool    // Whether the contour is the border of a hole
+	Parent int     // Index of the enclosing contour, -1 for the outermost contours
+}
+
+// FloatPoint represents a 2D point with real coordinates.
+type FloatPoint struct {
+	X, Y float64
+}
+
+// BezierCurve is a cubic Bezier curve.
+type BezierCurve struct {
+	Start, Control1, Control2, End FloatPoint
+}
+
+// SVGOptions configures the conversion of contours into SVG paths.
+type SVGOptions struct {
+	Epsilon  float64 // Ramer–Douglas–Peucker tolerance in pixels of the polygons (0 keeps every border pixel)
+	Curves   bool    // Fit cubic Bezier curves to the border pixels instead of drawing polygons
+	MaxError float64 // Largest distance in pixels between a fitted curve and the contour (0 means 1)
+	Color    Pixel   // Fill color of the shapes (on a 255 scale)
+}
+
+// clockwise lists the offsets of the 8 neighbors of a pixel in clockwise order, starting from the right one.
+var clockwise = [8]Point{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+
+// TraceContours finds the outer and hole borders of the set pixels (8-connectivity) with the border following
+// algorithm of Suzuki and Abe. Enclosing contours come before the contours they contain.
+func (pbm *PBM) TraceContours() []Contour {
+	// Working copy with a background frame: 0 is background, 1 is an unvisited set pixel,
+	// other values are the (signed) number of the border that went through the pixel
+	width, height := pbm.width+2, pbm.height+2
+	grid := make([][]int, height)
+	for y := range grid {
+		grid[y] = make([]int, width)
+	}
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if pbm.data[y][x] {
+				grid[y+1][x+1] = 1
+			}
+		}
+	}
+
+	// Border numbers start at 2, the frame being border 1
+	var contours []Contour
+	contourOf := func(border int) int { return border - 2 }
+	isHole := func(border int) bool { return border == 1 || contours[contourOf(border)].Hole }
+	parentOf := func(border int) int {
+		if border == 1 {
+			return -1
+		}
+		return contours[contourOf(border)].Parent
+	}
+
+	for y := 1; y < height-1; y++ {
+		lastBorder := 1 // Number of the last border met on this row
+		for x := 1; x < width-1; x++ {
+			var from Point
+			hole := false
+			switch {
+			case grid[y][x] == 1 && grid[y][x-1] == 0: // Outer border starts here
+				from = Point{x - 1, y}
+			case grid[y][x] >= 1 && grid[y][x+1] == 0: // Hole border starts here
+				from, hole = Point{x + 1, y}, true
+				if grid[y][x] > 1 {
+					lastBorder = grid[y][x]
+				}
+			default:
+				if grid[y][x] != 0 && grid[y][x] != 1 {
+					lastBorder = abs(grid[y][x])
+				}
+				continue
+			}
+
+			// The parent depends on whether the new border and the last one met are of the same kind
+			parent := contourOf(lastBorder)
+			if lastBorder == 1 {
+				parent = -1
+			}
+			if hole == isHole(lastBorder) {
+				parent = parentOf(lastBorder)
+			}
+			border := len(contours) + 2
+			contours = append(contours, Contour{Hole: hole, Parent: parent})
+			contours[len(contours)-1].Points = followBorder(grid, Point{x, y}, from, border)
+
+			if grid[y][x] != 1 {
+				lastBorder = abs(grid[y][x])
+			}
+		}
+	}
+	return contours
+}
+
+// followBorder follows the border starting at start, whose background neighbor from is the search origin,
+// marks its pixels with the border number and returns them in image coordinates.
+func followBorder(grid [][]int, start, from Point, border int) []Point {
+	directionOf := func(center, neighbor Point) int {
+		for i, offset := range clockwise {
+			if center.X+offset.X == neighbor.X && center.Y+offset.Y == neighbor.Y {
+				return i
+			}
+		}
+		return 0
+	}
+
+	// Look clockwise around the start for the first set pixel
+	first := -1
+	origin := directionOf(start, from)
+	for i := 0; i < 8; i++ {
+		direction := (origin + i) % 8
+		if grid[start.Y+clockwise[direction].Y][start.X+clockwise[direction].X] != 0 {
+			first = direction
+			break
+		}
+	}
+	if first < 0 { // Isolated pixel
+		grid[start.Y][start.X] = -border
+		return []Point{{start.X - 1, start.Y - 1}}
+	}
+
+	firstNeighbor := Point{start.X + clockwise[first].X, start.Y + clockwise[first].Y}
+	previous, current := firstNeighbor, start
+	var points []Point
+	for {
+		points = append(points, Point{current.X - 1, current.Y - 1})
+
+		// Look counter-clockwise around the current pixel, starting after the previous one
+		back := directionOf(current, previous)
+		eastExamined := false
+		var next Point
+		for i := 1; i <= 8; i++ {
+			direction := (back - i + 8) % 8
+			neighbor := Point{current.X + clockwise[direction].X, current.Y + clockwise[direction].Y}
+			if grid[neighbor.Y][neighbor.X] != 0 {
+				next = neighbor
+				break
+			}
+			if direction == 0 {
+				eastExamined = true
+			}
+		}
+
+		// Mark the pixel, negatively when the border leaves it towards the background on its right
+		if eastExamined {
+			grid[current.Y][current.X] = -border
+		} else if grid[current.Y][current.X] == 1 {
+			grid[current.Y][current.X] = border
+		}
+
+		if next == start && current == firstNeighbor {
+			return points
+		}
+		previous, current = current, next
+	}
+}
+
+// SimplifyContour reduces a closed contour to fewer vertices with the Ramer–Douglas–Peucker algorithm:
+// no removed point is farther than epsilon from the simplified polygon.
+func SimplifyContour(points []Point, epsilon float64) []Point {
+	if len(points) < 4 || epsilon <= 0 {
+		return append([]Point(nil), points...)
+	}
+
+	// Split the closed contour between its first point and the point farthest from it
+	farthest, farthestDistance := 0, -1
+	for i, point := range points {
+		dx, dy := point.X-points[0].X, point.Y-points[0].Y
+		if distance := dx*dx + dy*dy; distance > farthestDistance {
+			farthest, farthestDistance = i, distance
+		}
+	}
+
+	keep := make([]bool, len(points)+1) // Index len(points) stands for the first point closing the contour
+	keep[0], keep[farthest], keep[len(points)] = true, true, true
+	closed := append(append([]Point(nil), points...), points[0])
+	simplifyRange(closed, 0, farthest, epsilon, keep)
+	simplifyRange(closed, farthest, len(points), epsilon, keep)
+
+	var result []Point
+	for i, point := range points {
+		if keep[i] {
+			result = append(result, point)
+		}
+	}
+	return result
+}
+
+// simplifyRange marks the points kept between first and last (excluded) by the Ramer–Douglas–Peucker algorithm.
+func simplifyRange(points []Point, first, last int, epsilon float64, keep []bool) {
+	farthest, farthestDistance := -1, epsilon
+	for i := first + 1; i < last; i++ {
+		if distance := pointSegmentDistance(points[i], points[first], points[last]); distance > farthestDistance {
+			farthest, farthestDistance = i, distance
+		}
+	}
+	if farthest < 0 {
+		return
+	}
+	keep[farthest] = true
+	simplifyRange(points, first, farthest, epsilon, keep)
+	simplifyRange(points, farthest, last, epsilon, keep)
+}
+
+// pointSegmentDistance returns the distance between a point and the segment from a to b.
+func pointSegmentDistance(point, a, b Point) float64 {
+	dx, dy := float64(b.X-a.X), float64(b.Y-a.Y)
+	px, py := float64(point.X-a.X), float64(point.Y-a.Y)
+	length := dx*dx + dy*dy
+	if length == 0 {
+		return math.Hypot(px, py)
+	}
+	t := math.Min(math.Max((px*dx+py*dy)/length, 0), 1)
+	return math.Hypot(px-t*dx, py-t*dy)
+}
+
+// FitBezier approximates a closed contour with cubic Bezier curves, following Schneider's algorithm:
+// a curve is fitted by least squares and split where it strays farther than maxError from the points.
+func FitBezier(points []Point, maxError float64) []BezierCurve {
+	if maxError <= 0 {
+		maxError = 1
+	}
+	if len(points) < 2 {
+		return nil
+	}
+
+	closed := make([]FloatPoint, len(points)+1)
+	for i, point := range points {
+		closed[i] = FloatPoint{float64(point.X), float64(point.Y)}
+	}
+	closed[len(points)] = closed[0]
+	if len(points) < 4 {
+		return fitCubic(closed, tangentBetween(closed[0], closed[1]), tangentBetween(closed[len(points)], closed[len(points)-1]), maxError)
+	}
+
+	// Fit both halves of the contour, with a common tangent where they meet
+	half := len(points) / 2
+	tangentAtStart := tangentBetween(closed[len(points)-1], closed[1])
+	tangentAtHalf := tangentBetween(closed[half-1], closed[half+1])
+	curves := fitCubic(closed[:half+1], tangentAtStart, scaleVector(tangentAtHalf, -1), maxError)
+	return append(curves, fitCubic(closed[half:], tangentAtHalf, scaleVector(tangentAtStart, -1), maxError)...)
+}
+
+// fitCubic fits Bezier curves to the points, leaving the first point along leftTangent and reaching the last one
+// from the direction of rightTangent (which points backwards).
+func fitCubic(points []FloatPoint, leftTangent, rightTangent FloatPoint, maxError float64) []BezierCurve {
+	first, last := points[0], points[len(points)-1]
+	if len(points) == 2 { // Straight curve with its controls at a third of the chord
+		third := distanceBetween(first, last) / 3
+		return []BezierCurve{{
+			Start:    first,
+			Control1: addVectors(first, scaleVector(leftTangent, third)),
+			Control2: addVectors(last, scaleVector(rightTangent, third)),
+			End:      last,
+		}}
+	}
+
+	parameters := chordLengthParameters(points)
+	curve := generateBezier(points, parameters, leftTangent, rightTangent)
+	worst, split := bezierError(points, parameters, curve)
+	if worst < maxError {
+		return []BezierCurve{curve}
+	}
+
+	// Close enough: try to improve the parameters with Newton-Raphson steps before splitting
+	if worst < 4*maxError {
+		for iteration := 0; iteration < 4; iteration++ {
+			parameters = reparameterize(points, parameters, curve)
+			curve = generateBezier(points, parameters, leftTangent, rightTangent)
+			if worst, split = bezierError(points, parameters, curve); worst < maxError {
+				return []BezierCurve{curve}
+			}
+		}
+	}
+
+	split = min(max(split, 1), len(points)-2)
+	centerTangent := tangentBetween(points[split-1], points[split+1])
+	curves := fitCubic(points[:split+1], leftTangent, scaleVector(centerTangent, -1), maxError)
+	return append(curves, fitCubic(points[split:], centerTangent, rightTangent, maxError)...)
+}
+
+// generateBezier finds by least squares the lengths of the tangents giving the curve closest to the points.
+func generateBezier(points []FloatPoint, parameters []float64, leftTangent, rightTangent FloatPoint) BezierCurve {
+	first, last := points[0], points[len(points)-1]
+	var c00, c01, c11, x0, x1 float64
+	for i, point := range points {
+		u := parameters[i]
+		b0, b1, b2, b3 := bernstein(u)
+		a0, a1 := scaleVector(leftTangent, b1), scaleVector(rightTangent, b2)
+		c00 += dotVectors(a0, a0)
+		c01 += dotVectors(a0, a1)
+		c11 += dotVectors(a1, a1)
+		rest := subtractVectors(point, addVectors(scaleVector(first, b0+b1), scaleVector(last, b2+b3)))
+		x0 += dotVectors(a0, rest)
+		x1 += dotVectors(a1, rest)
+	}
+
+	alphaLeft, alphaRight := 0.0, 0.0
+	if determinant := c00*c11 - c01*c01; determinant != 0 {
+		alphaLeft = (x0*c11 - x1*c01) / determinant
+		alphaRight = (c00*x1 - c01*x0) / determinant
+	}
+	// Degenerate or negative lengths: fall back to the heuristic of the straight curve
+	chord := distanceBetween(first, last)
+	if epsilon := chord * 1e-6; alphaLeft < epsilon || alphaRight < epsilon {
+		alphaLeft, alphaRight = chord/3, chord/3
+	}
+	return BezierCurve{
+		Start:    first,
+		Control1: addVectors(first, scaleVector(leftTangent, alphaLeft)),
+		Control2: addVectors(last, scaleVector(rightTangent, alphaRight)),
+		End:      last,
+	}
+}
+
+// bezierError returns the largest distance between the points and the curve, and the index of the farthest point.
+func bezierError(points []FloatPoint, parameters []float64, curve BezierCurve) (float64, int) {
+	worst, index := 0.0, len(points)/2
+	for i := 1; i < len(points)-1; i++ {
+		if distance := distanceBetween(curve.At(parameters[i]), points[i]); distance > worst {
+			worst, index = distance, i
+		}
+	}
+	return worst, index
+}
+
+// reparameterize moves each parameter towards the closest point of the curve with a Newton-Raphson step.
+func reparameterize(points []FloatPoint, parameters []float64, curve BezierCurve) []float64 {
+	result := make([]float64, len(parameters))
+	for i, u := range parameters {
+		// Derivatives of the curve at u
+		d1 := scaleVector(addVectors(addVectors(
+			scaleVector(subtractVectors(curve.Control1, curve.Start), (1-u)*(1-u)),
+			scaleVector(subtractVectors(curve.Control2, curve.Control1), 2*u*(1-u))),
+			scaleVector(subtractVectors(curve.End, curve.Control2), u*u)), 3)
+		d2 := scaleVector(addVectors(
+			scaleVector(addVectors(subtractVectors(curve.Control2, scaleVector(curve.Control1, 2)), curve.Start), 1-u),
+			scaleVector(addVectors(subtractVectors(curve.End, scaleVector(curve.Control2, 2)), curve.Control1), u)), 6)
+		difference := subtractVectors(curve.At(u), points[i])
+		numerator := dotVectors(difference, d1)
+		denominator := dotVectors(d1, d1) + dotVectors(difference, d2)
+		result[i] = u
+		if denominator != 0 {
+			result[i] = math.Min(math.Max(u-numerator/denominator, 0), 1)
+		}
+	}
+	return result
+}
+
+// chordLengthParameters assigns to each point its relative distance along the polyline.
+func chordLengthParameters(points []FloatPoint) []float64 {
+	parameters := make([]float64, len(points))
+	for i := 1; i < len(points); i++ {
+		parameters[i] = parameters[i-1] + distanceBetween(points[i], points[i-1])
+	}
+	total := parameters[len(points)-1]
+	for i := range parameters {
+		if total > 0 {
+			parameters[i] /= total
+		}
+	}
+	return parameters
+}
+
+// At returns the point of the curve at the parameter t (0 to 1).
+func (curve BezierCurve) At(t float64) FloatPoint {
+	b0, b1, b2, b3 := bernstein(t)
+	return FloatPoint{
+		X: b0*curve.Start.X + b1*curve.Control1.X + b2*curve.Control2.X + b3*curve.End.X,
+		Y: b0*curve.Start.Y + b1*curve.Control1.Y + b2*curve.Control2.Y + b3*curve.End.Y,
+	}
+}
+
+// bernstein returns the cubic Bernstein polynomials at t.
+func bernstein(t float64) (float64, float64, float64, float64) {
+	s := 1 - t
+	return s * s * s, 3 * s * s * t, 3 * s * t * t, t * t * t
+}
+
+// tangentBetween returns the unit vector from a to b (the zero vector when they are equal).
+func tangentBetween(a, b FloatPoint) FloatPoint {
+	length := distanceBetween(a, b)
+	if length == 0 {
+		return FloatPoint{}
+	}
+	return FloatPoint{(b.X - a.X) / length, (b.Y - a.Y) / length}
+}
+
+func addVectors(a, b FloatPoint) FloatPoint          { return FloatPoint{a.X + b.X, a.Y + b.Y} }
+func subtractVectors(a, b FloatPoint) FloatPoint     { return FloatPoint{a.X - b.X, a.Y - b.Y} }
+func scaleVector(a FloatPoint, k float64) FloatPoint { return FloatPoint{a.X * k, a.Y * k} }
+func dotVectors(a, b FloatPoint) float64             { return a.X*b.X + a.Y*b.Y }
+func distanceBetween(a, b FloatPoint) float64        { return math.Hypot(a.X-b.X, a.Y-b.Y) }
+
+// ContoursToSVG returns an SVG document of the given size drawing the contours as filled shapes,
+// holes being cut out with the even-odd fill rule. Coordinates are those of the pixel centers.
+func ContoursToSVG(width, height int, contours []Contour, opts SVGOptions) string {
+	var path strings.Builder
+	for _, contour := range contours {
+		points := contour.Points
+		if len(points) == 0 {
+			continue
+		}
+		if path.Len() > 0 {
+			path.WriteByte(' ')
+		}
+		if opts.Curves && len(points) > 2 {
+			curves := FitBezier(points, opts.MaxError)
+			path.WriteString("M" + svgPoint(curves[0].Start))
+			for _, curve := range curves {
+				path.WriteString(" C" + svgPoint(curve.Control1) + " " + svgPoint(curve.Control2) + " " + svgPoint(curve.End))
+			}
+		} else {
+			for i, point := range SimplifyContour(points, opts.Epsilon) {
+				if i == 0 {
+					path.WriteString("M")
+				} else {
+					path.WriteString(" L")
+				}
+				path.WriteString(svgPoint(FloatPoint{float64(point.X), float64(point.Y)}))
+			}
+		}
+		path.WriteString(" Z")
+	}
+
+	var svg strings.Builder
+	svg.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&svg, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", width, height, width, height)
+	fmt.Fprintf(&svg, "<path fill=\"#%02x%02x%02x\" fill-rule=\"evenodd\" d=\"%s\"/>\n", opts.Color.R, opts.Color.G, opts.Color.B, path.String())
+	svg.WriteString("</svg>\n")
+	return svg.String()
+}
+
+// SaveSVG traces the contours of the PBM image and saves them as an SVG document.
+func (pbm *PBM) SaveSVG(filename string, opts SVGOptions) error {
+	svg := ContoursToSVG(pbm.width, pbm.height, pbm.TraceContours(), opts)
+	return os.WriteFile(filename, []byte(svg), 0644)
+}
+
+// svgPoint formats a point for SVG path data, moved to the center of its pixel.
+func svgPoint(point FloatPoint) string {
+	format := func(value float64) string {
+		return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+	}
+	return format(point.X+0.5) + "," + format(point.Y+0.5)
+}
+
+// DrawContours rasterizes traced contours with DrawFilledPolygon: outer contours are filled with color
+// and the inside of holes is restored to background, enclosing contours being drawn first.
+func (ppm *PPM) DrawContours(contours []Contour, color, background Pixel) {
+	for _, contour := range contours {
+		if !contour.Hole {
+			ppm.DrawFilledPolygon(contour.Points, color)
+			continue
+		}
+		// The pixels of a hole contour belong to the shape around the hole
+		ppm.DrawFilledPolygon(contour.Points, background)
+		ppm.DrawPolygon(contour.Points, color)
+	}
+}
@@ -0,0 +1,324 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// EdgeMode selects how pixels outside of the image are read by the filters.
+type EdgeMode int
+
+const (
+	EdgeClamp  EdgeMode = iota // Repeat the nearest edge pixel
+	EdgeWrap                   // Wrap around to the opposite edge
+	EdgeMirror                 // Reflect the image around its edge (the edge pixel is not repeated)
+	EdgeZero                   // Read zero outside of the image
+)
+
+// Kernel is a convolution kernel with float weights.
+type Kernel struct {
+	Width, Height    int       // Size of the kernel
+	Weights          []float64 // Weights stored row by row (Width * Height values)
+	AnchorX, AnchorY int       // Position of the kernel cell placed over the output pixel
+	Divisor          float64   // Value the weighted sum is divided by (0 means the sum of the weights, or 1 if it is 0)
+	Bias             float64   // Value added after the division, in channel units
+
+	row, column []float64 // Factors of a separable kernel (Weights = column x row), nil otherwise
+}
+
+// NewKernel creates a kernel from its weights given row by row, anchored at its center.
+func NewKernel(width, height int, weights []float64) (*Kernel, error) {
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("Invalid kernel size: %dx%d", width, height)
+	}
+	if len(weights) != width*height {
+		return nil, fmt.Errorf("Invalid kernel: %d weights for a %dx%d kernel", len(weights), width, height)
+	}
+	return &Kernel{
+		Width:   width,
+		Height:  height,
+		Weights: append([]float64(nil), weights...),
+		AnchorX: width / 2,
+		AnchorY: height / 2,
+	}, nil
+}
+
+// NewSeparableKernel creates the kernel equal to the outer product of a column and a row vector.
+// Convolution with a separable kernel is done in two one-dimensional passes, which is much faster for large kernels.
+func NewSeparableKernel(row, column []float64) *Kernel {
+	kernel := &Kernel{
+		Width:   len(row),
+		Height:  len(column),
+		Weights: make([]float64, len(row)*len(column)),
+		AnchorX: len(row) / 2,
+		AnchorY: len(column) / 2,
+		row:     append([]float64(nil), row...),
+		column:  append([]float64(nil), column...),
+	}
+	for y, columnWeight := range column {
+		for x, rowWeight := range row {
+			kernel.Weights[y*kernel.Width+x] = columnWeight * rowWeight
+		}
+	}
+	return kernel
+}
+
+// Separable reports whether the kernel was built from a row and a column vector and its weights are still
+// their outer product. A kernel whose Weights were edited afterwards is convolved in a single two-dimensional pass.
+func (k *Kernel) Separable() bool {
+	if k.row == nil || k.column == nil || len(k.row) != k.Width || len(k.column) != k.Height || len(k.Weights) != k.Width*k.Height {
+		return false
+	}
+	for y, columnWeight := range k.column {
+		for x, rowWeight := range k.row {
+			if k.Weights[y*k.Width+x] != columnWeight*rowWeight {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// divisor returns the effective divisor of the kernel.
+func (k *Kernel) divisor() float64 {
+	if k.Divisor != 0 {
+		return k.Divisor
+	}
+	sum := 0.0
+	for _, weight := range k.Weights {
+		sum += weight
+	}
+	if math.Abs(sum) < 1e-12 { // Derivative kernels sum to zero
+		return 1
+	}
+	return sum
+}
+
+// BoxKernel returns the (2*radius+1) square averaging kernel.
+func BoxKernel(radius int) *Kernel {
+	weights := make([]float64, 2*radius+1)
+	for i := range weights {
+		weights[i] = 1
+	}
+	return NewSeparableKernel(weights, weights)
+}
+
+// GaussianKernel returns a normalized Gaussian blur kernel of standard deviation sigma, truncated at 3 sigma.
+func GaussianKernel(sigma float64) *Kernel {
+	weights := gaussianWeights(sigma)
+	return NewSeparableKernel(weights, weights)
+}
+
+// SharpenKernel returns the 3x3 sharpening kernel.
+func SharpenKernel() *Kernel {
+	kernel, _ := NewKernel(3, 3, []float64{
+		0, -1, 0,
+		-1, 5, -1,
+		0, -1, 0,
+	})
+	return kernel
+}
+
+// EmbossKernel returns the 3x3 emboss kernel (light coming from the top left).
+func EmbossKernel() *Kernel {
+	kernel, _ := NewKernel(3, 3, []float64{
+		-2, -1, 0,
+		-1, 1, 1,
+		0, 1, 2,
+	})
+	return kernel
+}
+
+// LaplacianKernel returns the 3x3 Laplacian kernel (4-neighborhood).
+func LaplacianKernel() *Kernel {
+	kernel, _ := NewKernel(3, 3, []float64{
+		0, 1, 0,
+		1, -4, 1,
+		0, 1, 0,
+	})
+	return kernel
+}
+
+// SobelX returns the Sobel kernel computing the horizontal derivative.
+func SobelX() *Kernel {
+	return NewSeparableKernel([]float64{-1, 0, 1}, []float64{1, 2, 1})
+}
+
+// SobelY returns the Sobel kernel computing the vertical derivative.
+func SobelY() *Kernel {
+	return NewSeparableKernel([]float64{1, 2, 1}, []float64{-1, 0, 1})
+}
+
+// PrewittX returns the Prewitt kernel computing the horizontal derivative.
+func PrewittX() *Kernel {
+	return NewSeparableKernel([]float64{-1, 0, 1}, []float64{1, 1, 1})
+}
+
+// PrewittY returns the Prewitt kernel computing the vertical derivative.
+func PrewittY() *Kernel {
+	return NewSeparableKernel([]float64{1, 1, 1}, []float64{-1, 0, 1})
+}
+
+// ScharrX returns the Scharr kernel computing the horizontal derivative.
+func ScharrX() *Kernel {
+	return NewSeparableKernel([]float64{-1, 0, 1}, []float64{3, 10, 3})
+}
+
+// ScharrY returns the Scharr kernel computing the vertical derivative.
+func ScharrY() *Kernel {
+	return NewSeparableKernel([]float64{3, 10, 3}, []float64{-1, 0, 1})
+}
+
+// Convolve applies the kernel to the PGM image. The results are rounded and clamped to 0..max.
+func (pgm *PGM) Convolve(kernel *Kernel, edge EdgeMode) {
+	result := convolvePlane(uint8PlaneToFloat(pgm.data), kernel, edge)
+	pgm.data = floatPlaneToUint8(result, pgm.max)
+}
+
+// Convolve applies the kernel to the selected channels of the PPM image.
+func (ppm *PPM) Convolve(kernel *Kernel, edge EdgeMode, channels Channel) {
+	for _, channel := range channels.indices() {
+		result := convolvePlane(uint8PlaneToFloat(ppm.channelPlane(channel)), kernel, edge)
+		ppm.setChannelPlane(channel, floatPlaneToUint8(result, ppm.max))
+	}
+}
+
+// gaussianWeights returns the normalized one-dimensional Gaussian of standard deviation sigma, truncated at 3 sigma.
+func gaussianWeights(sigma float64) []float64 {
+	if sigma <= 0 {
+		return []float64{1}
+	}
+	radius := int(math.Ceil(3 * sigma))
+	weights := make([]float64, 2*radius+1)
+	sum := 0.0
+	for i := range weights {
+		offset := float64(i - radius)
+		weights[i] = math.Exp(-offset * offset / (2 * sigma * sigma))
+		sum += weights[i]
+	}
+	for i := range weights {
+		weights[i] /= sum
+	}
+	return weights
+}
+
+// edgeIndex maps a coordinate that may be outside of 0..size-1 according to the edge mode.
+// The boolean is false when the pixel must be read as zero.
+func edgeIndex(index, size int, edge EdgeMode) (int, bool) {
+	if index >= 0 && index < size {
+		return index, true
+	}
+	switch edge {
+	case EdgeWrap:
+		index %= size
+		if index < 0 {
+			index += size
+		}
+		return index, true
+	case EdgeMirror:
+		if size == 1 {
+			return 0, true
+		}
+		period := 2 * (size - 1) // Reflection without repeating the edge pixel
+		index %= period
+		if index < 0 {
+			index += period
+		}
+		if index >= size {
+			index = period - index
+		}
+		return index, true
+	case EdgeZero:
+		return 0, false
+	default: // EdgeClamp
+		return min(max(index, 0), size-1), true
+	}
+}
+
+// convolvePlane convolves a plane of float values with the kernel, using the separable fast path when possible.
+func convolvePlane(plane [][]float64, kernel *Kernel, edge EdgeMode) [][]float64 {
+	height := len(plane)
+	if height == 0 {
+		return plane
+	}
+	width := len(plane[0])
+	divisor := kernel.divisor()
+
+	result := make([][]float64, height)
+	for y := range result {
+		result[y] = make([]float64, width)
+	}
+
+	if kernel.Separable() {
+		// Horizontal pass with the row vector
+		temporary := make([][]float64, height)
+		for y := 0; y < height; y++ {
+			temporary[y] = make([]float64, width)
+			for x := 0; x < width; x++ {
+				sum := 0.0
+				for i, weight := range kernel.row {
+					if sx, ok := edgeIndex(x+i-kernel.AnchorX, width, edge); ok {
+						sum += weight * plane[y][sx]
+					}
+				}
+				temporary[y][x] = sum
+			}
+		}
+		// Vertical pass with the column vector
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				sum := 0.0
+				for j, weight := range kernel.column {
+					if sy, ok := edgeIndex(y+j-kernel.AnchorY, height, edge); ok {
+						sum += weight * temporary[sy][x]
+					}
+				}
+				result[y][x] = sum/divisor + kernel.Bias
+			}
+		}
+		return result
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sum := 0.0
+			for j := 0; j < kernel.Height; j++ {
+				sy, ok := edgeIndex(y+j-kernel.AnchorY, height, edge)
+				if !ok {
+					continue
+				}
+				for i := 0; i < kernel.Width; i++ {
+					if sx, ok := edgeIndex(x+i-kernel.AnchorX, width, edge); ok {
+						sum += kernel.Weights[j*kernel.Width+i] * plane[sy][sx]
+					}
+				}
+			}
+			result[y][x] = sum/divisor + kernel.Bias
+		}
+	}
+	return result
+}
+
+// uint8PlaneToFloat converts a plane of channel values to float values.
+func uint8PlaneToFloat(plane [][]uint8) [][]float64 {
+	result := make([][]float64, len(plane))
+	for y, row := range plane {
+		result[y] = make([]float64, len(row))
+		for x, value := range row {
+			result[y][x] = float64(value)
+		}
+	}
+	return result
+}
+
+// floatPlaneToUint8 rounds and clamps a plane of float values to channel values from 0 to maxValue.
+func floatPlaneToUint8(plane [][]float64, maxValue int) [][]uint8 {
+	result := make([][]uint8, len(plane))
+	for y, row := range plane {
+		result[y] = make([]uint8, len(row))
+		for x, value := range row {
+			result[y][x] = uint8(math.Round(math.Min(math.Max(value, 0), float64(maxValue))))
+		}
+	}
+	return result
+}
@@ -0,0 +1,334 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// MedianFilter replaces each pixel of the PGM image by the median of the (2*radius+1) square window around it.
+func (pgm *PGM) MedianFilter(radius int, edge EdgeMode) error {
+	return pgm.RankFilter(radius, 0.5, edge)
+}
+
+// MinFilter replaces each pixel of the PGM image by the minimum of its window (grayscale erosion with a square).
+func (pgm *PGM) MinFilter(radius int, edge EdgeMode) error {
+	return pgm.RankFilter(radius, 0, edge)
+}
+
+// MaxFilter replaces each pixel of the PGM image by the maximum of its window (grayscale dilation with a square).
+func (pgm *PGM) MaxFilter(radius int, edge EdgeMode) error {
+	return pgm.RankFilter(radius, 1, edge)
+}
+
+// RankFilter replaces each pixel of the PGM image by the value at the given rank (0 for the minimum, 0.5 for the median,
+// 1 for the maximum) of the sorted values of its window.
+// Large windows use a sliding histogram, whose cost per pixel grows linearly with the radius instead of quadratically.
+func (pgm *PGM) RankFilter(radius int, rank float64, edge EdgeMode) error {
+	if radius < 0 || rank < 0 || rank > 1 {
+		return fmt.Errorf("Invalid rank filter: radius %d, rank %v", radius, rank)
+	}
+	pgm.data = rankPlane(pgm.data, pgm.max, radius, rank, edge)
+	return nil
+}
+
+// MedianFilter applies a median filter to the selected channels of the PPM image.
+func (ppm *PPM) MedianFilter(radius int, edge EdgeMode, channels Channel) error {
+	return ppm.RankFilter(radius, 0.5, edge, channels)
+}
+
+// MinFilter applies a minimum filter to the selected channels of the PPM image.
+func (ppm *PPM) MinFilter(radius int, edge EdgeMode, channels Channel) error {
+	return ppm.RankFilter(radius, 0, edge, channels)
+}
+
+// MaxFilter applies a maximum filter to the selected channels of the PPM image.
+func (ppm *PPM) MaxFilter(radius int, edge EdgeMode, channels Channel) error {
+	return ppm.RankFilter(radius, 1, edge, channels)
+}
+
+// RankFilter applies a rank filter to the selected channels of the PPM image.
+func (ppm *PPM) RankFilter(radius int, rank float64, edge EdgeMode, channels Channel) error {
+	if radius < 0 || rank < 0 || rank > 1 {
+		return fmt.Errorf("Invalid rank filter: radius %d, rank %v", radius, rank)
+	}
+	for _, channel := range channels.indices() {
+		ppm.setChannelPlane(channel, rankPlane(ppm.channelPlane(channel), ppm.max, radius, rank, edge))
+	}
+	return nil
+}
+
+// Bilateral smooths the PGM image while preserving edges: neighbors are weighted by their distance (sigmaSpatial, in pixels)
+// and by their difference of value (sigmaRange, in gray levels). The window has a radius of radius pixels.
+func (pgm *PGM) Bilateral(radius int, sigmaSpatial, sigmaRange float64, edge EdgeMode) error {
+	if radius < 0 || sigmaSpatial <= 0 || sigmaRange <= 0 {
+		return fmt.Errorf("Invalid bilateral filter: radius %d, sigmas %v and %v", radius, sigmaSpatial, sigmaRange)
+	}
+	planes := [][][]float64{uint8PlaneToFloat(pgm.data)}
+	result := bilateralPlanes(planes, radius, sigmaSpatial, sigmaRange, edge)
+	pgm.data = floatPlaneToUint8(result[0], pgm.max)
+	return nil
+}
+
+// Bilateral smooths the PPM image while preserving edges, the range weight using the distance between colors.
+func (ppm *PPM) Bilateral(radius int, sigmaSpatial, sigmaRange float64, edge EdgeMode) error {
+	if radius < 0 || sigmaSpatial <= 0 || sigmaRange <= 0 {
+		return fmt.Errorf("Invalid bilateral filter: radius %d, sigmas %v and %v", radius, sigmaSpatial, sigmaRange)
+	}
+	result := bilateralPlanes(ppm.floatPlanes(), radius, sigmaSpatial, sigmaRange, edge)
+	ppm.setFloatPlanes(result)
+	return nil
+}
+
+// NonLocalMeans denoises the PGM image by averaging the pixels of a (2*searchRadius+1) window whose surrounding
+// (2*patchRadius+1) patch looks like the patch of the current pixel. h controls the strength of the filter, in gray levels.
+func (pgm *PGM) NonLocalMeans(searchRadius, patchRadius int, h float64, edge EdgeMode) error {
+	if searchRadius < 0 || patchRadius < 0 || h <= 0 {
+		return fmt.Errorf("Invalid non-local means filter: search radius %d, patch radius %d, h %v", searchRadius, patchRadius, h)
+	}
+	planes := [][][]float64{uint8PlaneToFloat(pgm.data)}
+	result := nonLocalMeansPlanes(planes, searchRadius, patchRadius, h, edge)
+	pgm.data = floatPlaneToUint8(result[0], pgm.max)
+	return nil
+}
+
+// NonLocalMeans denoises the PPM image with non-local means, comparing patches over the three channels.
+func (ppm *PPM) NonLocalMeans(searchRadius, patchRadius int, h float64, edge EdgeMode) error {
+	if searchRadius < 0 || patchRadius < 0 || h <= 0 {
+		return fmt.Errorf("Invalid non-local means filter: search radius %d, patch radius %d, h %v", searchRadius, patchRadius, h)
+	}
+	result := nonLocalMeansPlanes(ppm.floatPlanes(), searchRadius, patchRadius, h, edge)
+	ppm.setFloatPlanes(result)
+	return nil
+}
+
+// floatPlanes returns the red, green and blue channels of the PPM image as float planes.
+func (ppm *PPM) floatPlanes() [][][]float64 {
+	return [][][]float64{
+		uint8PlaneToFloat(ppm.channelPlane(0)),
+		uint8PlaneToFloat(ppm.channelPlane(1)),
+		uint8PlaneToFloat(ppm.channelPlane(2)),
+	}
+}
+
+// setFloatPlanes rounds and clamps three float planes into the red, green and blue channels of the PPM image.
+func (ppm *PPM) setFloatPlanes(planes [][][]float64) {
+	for channel, plane := range planes {
+		ppm.setChannelPlane(channel, floatPlaneToUint8(plane, ppm.max))
+	}
+}
+
+// sampleUint8 reads a value of a plane at a position that may be outside of it, according to the edge mode.
+func sampleUint8(plane [][]uint8, x, y, width, height int, edge EdgeMode) uint8 {
+	sx, okX := edgeIndex(x, width, edge)
+	sy, okY := edgeIndex(y, height, edge)
+	if !okX || !okY {
+		return 0
+	}
+	return plane[sy][sx]
+}
+
+// sampleFloat reads a value of a float plane at a position that may be outside of it, according to the edge mode.
+func sampleFloat(plane [][]float64, x, y, width, height int, edge EdgeMode) float64 {
+	sx, okX := edgeIndex(x, width, edge)
+	sy, okY := edgeIndex(y, height, edge)
+	if !okX || !okY {
+		return 0
+	}
+	return plane[sy][sx]
+}
+
+// rankPlane returns a plane where each value is replaced by the value at the given rank in its window.
+func rankPlane(plane [][]uint8, maxValue, radius int, rank float64, edge EdgeMode) [][]uint8 {
+	height := len(plane)
+	result := make([][]uint8, height)
+	if height == 0 {
+		return result
+	}
+	width := len(plane[0])
+	size := (2*radius + 1) * (2*radius + 1) // Number of values in a window
+	target := int(math.Round(rank * float64(size-1)))
+
+	if radius <= 2 { // Small windows: sorting is cheaper than maintaining a histogram
+		window := make([]int, 0, size)
+		for y := 0; y < height; y++ {
+			result[y] = make([]uint8, width)
+			for x := 0; x < width; x++ {
+				window = window[:0]
+				for dy := -radius; dy <= radius; dy++ {
+					for dx := -radius; dx <= radius; dx++ {
+						window = append(window, int(sampleUint8(plane, x+dx, y+dy, width, height, edge)))
+					}
+				}
+				sort.Ints(window)
+				result[y][x] = uint8(window[target])
+			}
+		}
+		return result
+	}
+
+	// Large windows: Huang's sliding histogram, updated column by column along each row
+	histogram := make([]int, 256)
+	for y := 0; y < height; y++ {
+		result[y] = make([]uint8, width)
+		for i := range histogram {
+			histogram[i] = 0
+		}
+		for dy := -radius; dy <= radius; dy++ { // Histogram of the first window of the row
+			for dx := -radius; dx <= radius; dx++ {
+				histogram[sampleUint8(plane, dx, y+dy, width, height, edge)]++
+			}
+		}
+
+		for x := 0; x < width; x++ {
+			if x > 0 { // Slide the window: remove the leaving column and add the entering one
+				for dy := -radius; dy <= radius; dy++ {
+					histogram[sampleUint8(plane, x-radius-1, y+dy, width, height, edge)]--
+					histogram[sampleUint8(plane, x+radius, y+dy, width, height, edge)]++
+				}
+			}
+
+			// Walk the histogram up to the wanted rank
+			count := 0
+			for value := 0; value <= 255; value++ {
+				count += histogram[value]
+				if count > target {
+					result[y][x] = uint8(min(value, maxValue))
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+// bilateralPlanes applies a joint bilateral filter to a set of planes, the range distance being computed over all of them.
+func bilateralPlanes(planes [][][]float64, radius int, sigmaSpatial, sigmaRange float64, edge EdgeMode) [][][]float64 {
+	height := len(planes[0])
+	width := 0
+	if height > 0 {
+		width = len(planes[0][0])
+	}
+
+	// Precompute the spatial weights of the window
+	side := 2*radius + 1
+	spatial := make([]float64, side*side)
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			spatial[(dy+radius)*side+dx+radius] = math.Exp(-float64(dx*dx+dy*dy) / (2 * sigmaSpatial * sigmaSpatial))
+		}
+	}
+	rangeFactor := -1 / (2 * sigmaRange * sigmaRange)
+
+	result := make([][][]float64, len(planes))
+	for i := range result {
+		result[i] = make([][]float64, height)
+		for y := range result[i] {
+			result[i][y] = make([]float64, width)
+		}
+	}
+
+	center := make([]float64, len(planes))
+	sums := make([]float64, len(planes))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			for i, plane := range planes {
+				center[i] = plane[y][x]
+				sums[i] = 0
+			}
+			totalWeight := 0.0
+			for dy := -radius; dy <= radius; dy++ {
+				for dx := -radius; dx <= radius; dx++ {
+					// Squared distance between the neighbor and the center over all planes
+					distance := 0.0
+					for i, plane := range planes {
+						difference := sampleFloat(plane, x+dx, y+dy, width, height, edge) - center[i]
+						distance += difference * difference
+					}
+					weight := spatial[(dy+radius)*side+dx+radius] * math.Exp(distance*rangeFactor)
+					for i, plane := range planes {
+						sums[i] += weight * sampleFloat(plane, x+dx, y+dy, width, height, edge)
+					}
+					totalWeight += weight
+				}
+			}
+			for i := range planes {
+				result[i][y][x] = sums[i] / totalWeight
+			}
+		}
+	}
+	return result
+}
+
+// nonLocalMeansPlanes applies non-local means to a set of planes, patches being compared over all of them.
+// For each offset of the search window, the patch distances of every pixel are obtained in constant time
+// from an integral image of the squared differences.
+func nonLocalMeansPlanes(planes [][][]float64, searchRadius, patchRadius int, h float64, edge EdgeMode) [][][]float64 {
+	height := len(planes[0])
+	width := 0
+	if height > 0 {
+		width = len(planes[0][0])
+	}
+	patchArea := float64((2*patchRadius + 1) * (2*patchRadius + 1) * len(planes))
+
+	sums := make([][][]float64, len(planes))
+	for i := range sums {
+		sums[i] = make([][]float64, height)
+		for y := range sums[i] {
+			sums[i][y] = make([]float64, width)
+		}
+	}
+	weights := make([][]float64, height)
+	for y := range weights {
+		weights[y] = make([]float64, width)
+	}
+
+	// Integral image covering the image extended by the patch radius on every side
+	extendedWidth, extendedHeight := width+2*patchRadius, height+2*patchRadius
+	integral := make([][]float64, extendedHeight+1)
+	for y := range integral {
+		integral[y] = make([]float64, extendedWidth+1)
+	}
+
+	for dy := -searchRadius; dy <= searchRadius; dy++ {
+		for dx := -searchRadius; dx <= searchRadius; dx++ {
+			// Integral image of the squared differences between the image and its shifted copy
+			for ey := 0; ey < extendedHeight; ey++ {
+				y := ey - patchRadius
+				for ex := 0; ex < extendedWidth; ex++ {
+					x := ex - patchRadius
+					difference := 0.0
+					for _, plane := range planes {
+						d := sampleFloat(plane, x, y, width, height, edge) - sampleFloat(plane, x+dx, y+dy, width, height, edge)
+						difference += d * d
+					}
+					integral[ey+1][ex+1] = difference + integral[ey][ex+1] + integral[ey+1][ex] - integral[ey][ex]
+				}
+			}
+
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					// Patch centered on (x, y) spans extended coordinates [x, x+2*patchRadius]
+					top, left := y, x
+					bottom, right := y+2*patchRadius+1, x+2*patchRadius+1
+					distance := (integral[bottom][right] - integral[top][right] - integral[bottom][left] + integral[top][left]) / patchArea
+					weight := math.Exp(-distance / (h * h))
+
+					weights[y][x] += weight
+					for i, plane := range planes {
+						sums[i][y][x] += weight * sampleFloat(plane, x+dx, y+dy, width, height, edge)
+					}
+				}
+			}
+		}
+	}
+
+	for i := range sums {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				sums[i][y][x] /= weights[y][x]
+			}
+		}
+	}
+	return sums
+}
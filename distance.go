@@ -0,0 +1,369 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// DistanceMetric selects how the distance between two pixels is measured.
+type DistanceMetric int
+
+const (
+	DistanceEuclidean  DistanceMetric = iota // Exact straight-line distance
+	DistanceChamfer                          // 3-4 chamfer approximation of the Euclidean distance
+	DistanceManhattan                        // Sum of the horizontal and vertical distances
+	DistanceChessboard                       // Largest of the horizontal and vertical distances
+)
+
+// noFeature marks the pixels of an image that has no set pixel at all.
+var noFeature = Point{-1, -1}
+
+// DistanceTransform returns, for every pixel, its distance to the nearest set pixel of the PBM image.
+// Set pixels are at distance 0; every distance is +Inf when the image has no set pixel.
+func (pbm *PBM) DistanceTransform(metric DistanceMetric) ([][]float64, error) {
+	distances, _, err := featureTransform(pbm.data, pbm.width, pbm.height, metric)
+	return distances, err
+}
+
+// DistanceTransformPGM returns the distance transform as a PGM image scaled so that the farthest pixel is 255.
+func (pbm *PBM) DistanceTransformPGM(metric DistanceMetric) (*PGM, error) {
+	distances, err := pbm.DistanceTransform(metric)
+	if err != nil {
+		return nil, err
+	}
+
+	farthest := 0.0
+	for _, row := range distances {
+		for _, distance := range row {
+			if !math.IsInf(distance, 1) {
+				farthest = math.Max(farthest, distance)
+			}
+		}
+	}
+
+	pgm := &PGM{
+		data:        make([][]uint8, pbm.height),
+		width:       pbm.width,
+		height:      pbm.height,
+		magicNumber: "P2",
+		max:         255,
+	}
+	for y, row := range distances {
+		pgm.data[y] = make([]uint8, pbm.width)
+		for x, distance := range row {
+			switch {
+			case math.IsInf(distance, 1):
+				pgm.data[y][x] = 255
+			case farthest > 0:
+				pgm.data[y][x] = uint8(math.Round(distance / farthest * 255))
+			}
+		}
+	}
+	return pgm, nil
+}
+
+// FeatureTransform returns, for every pixel, the position of the nearest set pixel of the PBM image.
+// Every position is (-1, -1) when the image has no set pixel.
+func (pbm *PBM) FeatureTransform(metric DistanceMetric) ([][]Point, error) {
+	_, features, err := featureTransform(pbm.data, pbm.width, pbm.height, metric)
+	return features, err
+}
+
+// MedialAxis returns the skeleton of the set pixels made of the centers of the largest inscribed disks.
+// A set pixel belongs to it when the directions to the nearest background pixel from it and from one of its
+// neighbors differ by more than 60 degrees. The outside of the image counts as background.
+func (pbm *PBM) MedialAxis() *PBM {
+	// Background mask with a border of one pixel standing for the outside of the image
+	width, height := pbm.width+2, pbm.height+2
+	background := newMask(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			background[y][x] = x == 0 || y == 0 || x == width-1 || y == height-1 || !pbm.data[y-1][x-1]
+		}
+	}
+	distances, features, _ := featureTransform(background, width, height, DistanceEuclidean)
+
+	result := &PBM{
+		data:        newMask(pbm.width, pbm.height),
+		width:       pbm.width,
+		height:      pbm.height,
+		magicNumber: pbm.magicNumber,
+	}
+	for y := 1; y < height-1; y++ {
+		for x := 1; x < width-1; x++ {
+			if background[y][x] {
+				continue
+			}
+			for _, offset := range []Point{{1, 0}, {0, 1}, {1, 1}, {-1, 1}} { // Each pair of neighbors is seen once
+				nx, ny := x+offset.X, y+offset.Y
+				if background[ny][nx] {
+					continue
+				}
+				ux, uy := float64(features[y][x].X-x), float64(features[y][x].Y-y)
+				vx, vy := float64(features[ny][nx].X-nx), float64(features[ny][nx].Y-ny)
+				if ux*vx+uy*vy >= 0.5*math.Hypot(ux, uy)*math.Hypot(vx, vy) { // Angle of 60 degrees or less
+					continue
+				}
+				// Keep the pixel of the pair farther from the background
+				if distances[y][x] >= distances[ny][nx] {
+					result.data[y-1][x-1] = true
+				} else {
+					result.data[ny-1][nx-1] = true
+				}
+			}
+		}
+	}
+	return result
+}
+
+// Offset grows the set pixels by the given distance, or shrinks them when the distance is negative.
+// Growing sets every pixel within distance of a set pixel; shrinking keeps the set pixels farther than -distance
+// from the background.
+func (pbm *PBM) Offset(distance float64, metric DistanceMetric) error {
+	if distance >= 0 {
+		distances, err := pbm.DistanceTransform(metric)
+		if err != nil {
+			return err
+		}
+		for y := 0; y < pbm.height; y++ {
+			for x := 0; x < pbm.width; x++ {
+				pbm.data[y][x] = distances[y][x] <= distance
+			}
+		}
+		return nil
+	}
+
+	background := newMask(pbm.width, pbm.height)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			background[y][x] = !pbm.data[y][x]
+		}
+	}
+	distances, _, err := featureTransform(background, pbm.width, pbm.height, metric)
+	if err != nil {
+		return err
+	}
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			pbm.data[y][x] = distances[y][x] > -distance
+		}
+	}
+	return nil
+}
+
+// Voronoi colors every pixel after the nearest connected component (8-connectivity) of set pixels.
+// The colors are picked as in Labeling.Render and depend only on the seed.
+func (pbm *PBM) Voronoi(metric DistanceMetric, seed int64) (*PPM, error) {
+	labeling, err := pbm.Label(Connectivity8)
+	if err != nil {
+		return nil, err
+	}
+	_, features, err := featureTransform(pbm.data, pbm.width, pbm.height, metric)
+	if err != nil {
+		return nil, err
+	}
+	colors := labelColors(labeling.Count(), seed)
+	return renderRegions(features, pbm.width, pbm.height, func(feature Point) Pixel {
+		return colors[labeling.Labels[feature.Y][feature.X]]
+	}), nil
+}
+
+// VoronoiRegions renders the Voronoi diagram of the seed points: every pixel takes the color of its nearest seed.
+// colors[i] is the color of seeds[i].
+func VoronoiRegions(width, height int, seeds []Point, colors []Pixel, metric DistanceMetric) (*PPM, error) {
+	if len(seeds) == 0 || len(colors) != len(seeds) {
+		return nil, fmt.Errorf("Voronoi regions need at least one seed and one color per seed (got %d seeds, %d colors)", len(seeds), len(colors))
+	}
+
+	mask := newMask(width, height)
+	owner := make(map[Point]int, len(seeds)) // Index of the seed at each seed position
+	for i, seed := range seeds {
+		if seed.X < 0 || seed.X >= width || seed.Y < 0 || seed.Y >= height {
+			return nil, fmt.Errorf("Seed point (%d, %d) is outside of the image", seed.X, seed.Y)
+		}
+		mask[seed.Y][seed.X] = true
+		owner[seed] = i
+	}
+
+	_, features, err := featureTransform(mask, width, height, metric)
+	if err != nil {
+		return nil, err
+	}
+	return renderRegions(features, width, height, func(feature Point) Pixel {
+		return colors[owner[feature]]
+	}), nil
+}
+
+// renderRegions creates a PPM image coloring each pixel after its feature point.
+func renderRegions(features [][]Point, width, height int, color func(feature Point) Pixel) *PPM {
+	ppm := &PPM{
+		data:        make([][]Pixel, height),
+		width:       width,
+		height:      height,
+		magicNumber: "P3",
+		max:         255,
+	}
+	for y := 0; y < height; y++ {
+		ppm.data[y] = make([]Pixel, width)
+		for x := 0; x < width; x++ {
+			if features[y][x] != noFeature {
+				ppm.data[y][x] = color(features[y][x])
+			}
+		}
+	}
+	return ppm
+}
+
+// featureTransform returns the distance from every pixel to the nearest set pixel of the mask, and that pixel.
+func featureTransform(mask [][]bool, width, height int, metric DistanceMetric) ([][]float64, [][]Point, error) {
+	switch metric {
+	case DistanceEuclidean:
+		distances, features := euclideanFeatureTransform(mask, width, height)
+		return distances, features, nil
+	case DistanceChamfer:
+		distances, features := chamferFeatureTransform(mask, width, height, 1, 4.0/3)
+		return distances, features, nil
+	case DistanceManhattan:
+		distances, features := chamferFeatureTransform(mask, width, height, 1, math.Inf(1))
+		return distances, features, nil
+	case DistanceChessboard:
+		distances, features := chamferFeatureTransform(mask, width, height, 1, 1)
+		return distances, features, nil
+	}
+	return nil, nil, fmt.Errorf("Unsupported distance metric: %d", metric)
+}
+
+// euclideanFeatureTransform computes the exact Euclidean feature transform with the algorithm of Felzenszwalb
+// and Huttenlocher: nearest set pixels are first found along each column, then the lower envelope of the
+// parabolas rooted at these column results gives the nearest set pixel along each row.
+func euclideanFeatureTransform(mask [][]bool, width, height int) ([][]float64, [][]Point) {
+	// Column pass: squared distance to, and row of, the nearest set pixel in the same column
+	columnDistance := make([][]float64, height)
+	columnFeature := make([][]int, height)
+	for y := 0; y < height; y++ {
+		columnDistance[y] = make([]float64, width)
+		columnFeature[y] = make([]int, width)
+	}
+	for x := 0; x < width; x++ {
+		nearest := -1
+		for y := 0; y < height; y++ { // Downwards
+			if mask[y][x] {
+				nearest = y
+			}
+			columnFeature[y][x] = nearest
+		}
+		nearest = -1
+		for y := height - 1; y >= 0; y-- { // Upwards, keeping the closest of both directions
+			if mask[y][x] {
+				nearest = y
+			}
+			if nearest >= 0 && (columnFeature[y][x] < 0 || nearest-y < y-columnFeature[y][x]) {
+				columnFeature[y][x] = nearest
+			}
+			if columnFeature[y][x] < 0 {
+				columnDistance[y][x] = math.Inf(1)
+			} else {
+				dy := float64(columnFeature[y][x] - y)
+				columnDistance[y][x] = dy * dy
+			}
+		}
+	}
+
+	// Row pass: lower envelope of the parabolas (x - q)² + columnDistance[q] of the columns q having a set pixel
+	distances := make([][]float64, height)
+	features := make([][]Point, height)
+	sites := make([]int, 0, width)      // Columns of the parabolas of the envelope
+	starts := make([]float64, 0, width) // Abscissa from which each parabola is the lowest
+	for y := 0; y < height; y++ {
+		distances[y] = make([]float64, width)
+		features[y] = make([]Point, width)
+		f := columnDistance[y]
+
+		sites, starts = sites[:0], starts[:0]
+		for q := 0; q < width; q++ {
+			if math.IsInf(f[q], 1) {
+				continue
+			}
+			start := math.Inf(-1)
+			for len(sites) > 0 {
+				p := sites[len(sites)-1]
+				// Intersection of the parabolas rooted at p and q
+				start = (f[q] + float64(q*q) - f[p] - float64(p*p)) / float64(2*(q-p))
+				if start > starts[len(starts)-1] {
+					break
+				}
+				sites, starts = sites[:len(sites)-1], starts[:len(starts)-1]
+				start = math.Inf(-1)
+			}
+			sites, starts = append(sites, q), append(starts, start)
+		}
+
+		if len(sites) == 0 { // No set pixel in the whole image
+			for x := 0; x < width; x++ {
+				distances[y][x] = math.Inf(1)
+				features[y][x] = noFeature
+			}
+			continue
+		}
+		k := 0
+		for x := 0; x < width; x++ {
+			for k+1 < len(sites) && starts[k+1] < float64(x) {
+				k++
+			}
+			q := sites[k]
+			dx := float64(x - q)
+			distances[y][x] = math.Sqrt(dx*dx + f[q])
+			features[y][x] = Point{q, columnFeature[y][q]}
+		}
+	}
+	return distances, features
+}
+
+// chamferFeatureTransform propagates the nearest set pixel with two raster scans, a step costing orthogonal
+// between side neighbors and diagonal between corner neighbors.
+func chamferFeatureTransform(mask [][]bool, width, height int, orthogonal, diagonal float64) ([][]float64, [][]Point) {
+	distances := make([][]float64, height)
+	features := make([][]Point, height)
+	for y := 0; y < height; y++ {
+		distances[y] = make([]float64, width)
+		features[y] = make([]Point, width)
+		for x := 0; x < width; x++ {
+			if mask[y][x] {
+				features[y][x] = Point{x, y}
+			} else {
+				distances[y][x] = math.Inf(1)
+				features[y][x] = noFeature
+			}
+		}
+	}
+
+	relax := func(x, y, nx, ny int, step float64) {
+		if nx < 0 || nx >= width || ny < 0 || ny >= height {
+			return
+		}
+		if candidate := distances[ny][nx] + step; candidate < distances[y][x] {
+			distances[y][x] = candidate
+			features[y][x] = features[ny][nx]
+		}
+	}
+
+	// Forward scan looks at the neighbors already visited above and to the left
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			relax(x, y, x-1, y-1, diagonal)
+			relax(x, y, x, y-1, orthogonal)
+			relax(x, y, x+1, y-1, diagonal)
+			relax(x, y, x-1, y, orthogonal)
+		}
+	}
+	// Backward scan looks at the neighbors below and to the right
+	for y := height - 1; y >= 0; y-- {
+		for x := width - 1; x >= 0; x-- {
+			relax(x, y, x+1, y+1, diagonal)
+			relax(x, y, x, y+1, orthogonal)
+			relax(x, y, x-1, y+1, diagonal)
+			relax(x, y, x+1, y, orthogonal)
+		}
+	}
+	return distances, features
+}
@@ -0,0 +1,328 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// ThresholdMethod selects how a gray level is compared against a threshold when converting to PBM.
+type ThresholdMethod int
+
+const (
+	ThresholdFixed   ThresholdMethod = iota // Single global threshold (max/2 unless ToPBMOptions.Threshold is set)
+	ThresholdOtsu                           // Global threshold chosen by Otsu's method
+	ThresholdNiblack                        // Local adaptive threshold: mean + k * standard deviation
+	ThresholdSauvola                        // Local adaptive threshold: mean * (1 + k * (deviation / R - 1))
+)
+
+// DitherMethod selects the dithering algorithm used when converting to PBM.
+type DitherMethod int
+
+const (
+	DitherNone              DitherMethod = iota // No dithering, the ThresholdMethod is used instead
+	DitherFloydSteinberg                        // Floyd–Steinberg error diffusion
+	DitherAtkinson                              // Atkinson error diffusion (diffuses 3/4 of the error)
+	DitherJarvisJudiceNinke                     // Jarvis–Judice–Ninke error diffusion
+	DitherSierra                                // Sierra (three-row) error diffusion
+	DitherBayer                                 // Ordered dithering with a Bayer matrix
+)
+
+// ToPBMOptions configures the conversion performed by PGM.ToPBMWithOptions and PPM.ToPBMWithOptions.
+type ToPBMOptions struct {
+	Dither     DitherMethod    // Dithering algorithm, DitherNone to use Threshold instead
+	Serpentine bool            // Alternate the scan direction on each row (error diffusion only)
+	BayerSize  int             // Size of the Bayer matrix: 2, 4, 8 or 16 (0 means 4)
+	Method     ThresholdMethod // Threshold algorithm used when Dither is DitherNone
+	Threshold  int             // Fixed threshold for ThresholdFixed (0 means max/2)
+	WindowSize int             // Side of the local window for Niblack and Sauvola (0 means 15)
+	K          float64         // k parameter for Niblack (0 means -0.2) and Sauvola (0 means 0.5)
+	R          float64         // Dynamic range of the deviation for Sauvola (0 means max/2)
+}
+
+// diffusionWeight is one entry of an error diffusion kernel.
+type diffusionWeight struct {
+	dx, dy int     // Offset of the neighbor receiving the error
+	weight float64 // Fraction of the error given to that neighbor
+}
+
+// diffusionKernels holds the error diffusion kernels for each dithering method.
+var diffusionKernels = map[DitherMethod][]diffusionWeight{
+	DitherFloydSteinberg: {
+		{1, 0, 7.0 / 16}, {-1, 1, 3.0 / 16}, {0, 1, 5.0 / 16}, {1, 1, 1.0 / 16},
+	},
+	DitherAtkinson: {
+		{1, 0, 1.0 / 8}, {2, 0, 1.0 / 8},
+		{-1, 1, 1.0 / 8}, {0, 1, 1.0 / 8}, {1, 1, 1.0 / 8},
+		{0, 2, 1.0 / 8},
+	},
+	DitherJarvisJudiceNinke: {
+		{1, 0, 7.0 / 48}, {2, 0, 5.0 / 48},
+		{-2, 1, 3.0 / 48}, {-1, 1, 5.0 / 48}, {0, 1, 7.0 / 48}, {1, 1, 5.0 / 48}, {2, 1, 3.0 / 48},
+		{-2, 2, 1.0 / 48}, {-1, 2, 3.0 / 48}, {0, 2, 5.0 / 48}, {1, 2, 3.0 / 48}, {2, 2, 1.0 / 48},
+	},
+	DitherSierra: {
+		{1, 0, 5.0 / 32}, {2, 0, 3.0 / 32},
+		{-2, 1, 2.0 / 32}, {-1, 1, 4.0 / 32}, {0, 1, 5.0 / 32}, {1, 1, 4.0 / 32}, {2, 1, 2.0 / 32},
+		{-1, 2, 2.0 / 32}, {0, 2, 3.0 / 32}, {1, 2, 2.0 / 32},
+	},
+}
+
+// ToPBMWithOptions converts a PGM image to a PBM image using the dithering or threshold method described by opts.
+func (pgm *PGM) ToPBMWithOptions(opts ToPBMOptions) (*PBM, error) {
+	pbm := &PBM{
+		data:        make([][]bool, pgm.height), // Create a new PBM image with the same dimensions
+		width:       pgm.width,
+		height:      pgm.height,
+		magicNumber: "P1",
+	}
+	for rowIndex := range pbm.data {
+		pbm.data[rowIndex] = make([]bool, pgm.width)
+	}
+
+	var err error
+	switch opts.Dither {
+	case DitherNone:
+		err = pgm.thresholdInto(pbm, opts)
+	case DitherBayer:
+		err = pgm.orderedDitherInto(pbm, opts.BayerSize)
+	default:
+		kernel, ok := diffusionKernels[opts.Dither]
+		if !ok { // Unknown dithering method
+			return nil, fmt.Errorf("Unsupported dither method: %d", opts.Dither)
+		}
+		pgm.diffuseInto(pbm, kernel, opts.Serpentine)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pbm, nil
+}
+
+// ToPBMWithOptions converts the PPM image to PBM using the dithering or threshold method described by opts.
+// The pixels are first reduced to gray levels with ToPGM.
+func (ppm *PPM) ToPBMWithOptions(opts ToPBMOptions) (*PBM, error) {
+	return ppm.ToPGM().ToPBMWithOptions(opts)
+}
+
+// OtsuThreshold returns the gray level that best separates the image into two classes according to Otsu's method.
+// Pixels less than or equal to the returned value belong to the dark class.
+func (pgm *PGM) OtsuThreshold() int {
+	histogram := make([]float64, pgm.max+1) // Histogram of the gray levels
+	for _, row := range pgm.data {
+		for _, value := range row {
+			histogram[min(int(value), pgm.max)]++
+		}
+	}
+
+	total := float64(pgm.width * pgm.height) // Number of pixels in the image
+	sumAll := 0.0                            // Sum of all gray levels
+	for level, count := range histogram {
+		sumAll += float64(level) * count
+	}
+
+	bestThreshold, bestVariance := 0, -1.0
+	weightBackground, sumBackground := 0.0, 0.0
+	for level, count := range histogram { // Try every possible threshold
+		weightBackground += count
+		if weightBackground == 0 {
+			continue
+		}
+		weightForeground := total - weightBackground
+		if weightForeground == 0 {
+			break
+		}
+		sumBackground += float64(level) * count
+
+		meanBackground := sumBackground / weightBackground
+		meanForeground := (sumAll - sumBackground) / weightForeground
+
+		// Keep the threshold maximizing the between-class variance
+		variance := weightBackground * weightForeground * (meanBackground - meanForeground) * (meanBackground - meanForeground)
+		if variance > bestVariance {
+			bestVariance = variance
+			bestThreshold = level
+		}
+	}
+	return bestThreshold
+}
+
+// thresholdInto fills pbm by comparing each pixel against a global or local threshold.
+func (pgm *PGM) thresholdInto(pbm *PBM, opts ToPBMOptions) error {
+	switch opts.Method {
+	case ThresholdFixed:
+		threshold := opts.Threshold
+		if threshold == 0 { // Default to the historical max/2 threshold
+			threshold = pgm.max / 2
+		}
+		for rowIndex := 0; rowIndex < pgm.height; rowIndex++ {
+			for colIndex := 0; colIndex < pgm.width; colIndex++ {
+				pbm.data[rowIndex][colIndex] = int(pgm.data[rowIndex][colIndex]) < threshold
+			}
+		}
+	case ThresholdOtsu:
+		threshold := pgm.OtsuThreshold()
+		for rowIndex := 0; rowIndex < pgm.height; rowIndex++ {
+			for colIndex := 0; colIndex < pgm.width; colIndex++ {
+				pbm.data[rowIndex][colIndex] = int(pgm.data[rowIndex][colIndex]) <= threshold
+			}
+		}
+	case ThresholdNiblack, ThresholdSauvola:
+		pgm.localThresholdInto(pbm, opts)
+	default:
+		return fmt.Errorf("Unsupported threshold method: %d", opts.Method)
+	}
+	return nil
+}
+
+// localThresholdInto fills pbm using the Niblack or Sauvola local adaptive threshold.
+// Local means and deviations are computed in constant time per pixel with integral images.
+func (pgm *PGM) localThresholdInto(pbm *PBM, opts ToPBMOptions) {
+	window := opts.WindowSize
+	if window <= 0 {
+		window = 15
+	}
+	radius := window / 2
+
+	k := opts.K
+	if k == 0 { // Defaults recommended by the original papers
+		if opts.Method == ThresholdNiblack {
+			k = -0.2
+		} else {
+			k = 0.5
+		}
+	}
+	dynamicRange := opts.R
+	if dynamicRange == 0 {
+		dynamicRange = float64(pgm.max) / 2
+	}
+
+	// Integral images of the values and of their squares, with an extra leading row and column of zeros
+	sum := make([][]float64, pgm.height+1)
+	sumSquares := make([][]float64, pgm.height+1)
+	for rowIndex := range sum {
+		sum[rowIndex] = make([]float64, pgm.width+1)
+		sumSquares[rowIndex] = make([]float64, pgm.width+1)
+	}
+	for rowIndex := 0; rowIndex < pgm.height; rowIndex++ {
+		for colIndex := 0; colIndex < pgm.width; colIndex++ {
+			value := float64(pgm.data[rowIndex][colIndex])
+			sum[rowIndex+1][colIndex+1] = value + sum[rowIndex][colIndex+1] + sum[rowIndex+1][colIndex] - sum[rowIndex][colIndex]
+			sumSquares[rowIndex+1][colIndex+1] = value*value + sumSquares[rowIndex][colIndex+1] + sumSquares[rowIndex+1][colIndex] - sumSquares[rowIndex][colIndex]
+		}
+	}
+
+	for rowIndex := 0; rowIndex < pgm.height; rowIndex++ {
+		top, bottom := max(rowIndex-radius, 0), min(rowIndex+radius+1, pgm.height) // Window rows clipped to the image
+		for colIndex := 0; colIndex < pgm.width; colIndex++ {
+			left, right := max(colIndex-radius, 0), min(colIndex+radius+1, pgm.width) // Window columns clipped to the image
+
+			count := float64((bottom - top) * (right - left))
+			windowSum := sum[bottom][right] - sum[top][right] - sum[bottom][left] + sum[top][left]
+			windowSquares := sumSquares[bottom][right] - sumSquares[top][right] - sumSquares[bottom][left] + sumSquares[top][left]
+
+			mean := windowSum / count
+			deviation := math.Sqrt(math.Max(windowSquares/count-mean*mean, 0))
+
+			var threshold float64
+			if opts.Method == ThresholdNiblack {
+				threshold = mean + k*deviation
+			} else {
+				threshold = mean * (1 + k*(deviation/dynamicRange-1))
+			}
+			pbm.data[rowIndex][colIndex] = float64(pgm.data[rowIndex][colIndex]) <= threshold
+		}
+	}
+}
+
+// diffuseInto fills pbm with error diffusion dithering using the given kernel.
+func (pgm *PGM) diffuseInto(pbm *PBM, kernel []diffusionWeight, serpentine bool) {
+	// Working copy of the image in floating point so that the diffused error is not truncated
+	values := make([][]float64, pgm.height)
+	for rowIndex := range values {
+		values[rowIndex] = make([]float64, pgm.width)
+		for colIndex := range values[rowIndex] {
+			values[rowIndex][colIndex] = float64(pgm.data[rowIndex][colIndex])
+		}
+	}
+
+	maxFloat := float64(pgm.max)
+	for rowIndex := 0; rowIndex < pgm.height; rowIndex++ {
+		reverse := serpentine && rowIndex%2 == 1 // Scan right to left on odd rows in serpentine mode
+		for step := 0; step < pgm.width; step++ {
+			colIndex := step
+			if reverse {
+				colIndex = pgm.width - step - 1
+			}
+
+			// Quantize the pixel to black or white
+			oldValue := values[rowIndex][colIndex]
+			newValue := 0.0
+			if oldValue >= maxFloat/2 {
+				newValue = maxFloat
+			}
+			pbm.data[rowIndex][colIndex] = newValue == 0 // Black pixels are stored as true
+			quantError := oldValue - newValue
+
+			// Spread the quantization error over the neighbors not yet visited
+			for _, entry := range kernel {
+				dx := entry.dx
+				if reverse { // Mirror the kernel when scanning right to left
+					dx = -dx
+				}
+				x, y := colIndex+dx, rowIndex+entry.dy
+				if x >= 0 && x < pgm.width && y < pgm.height {
+					values[y][x] += quantError * entry.weight
+				}
+			}
+		}
+	}
+}
+
+// orderedDitherInto fills pbm with ordered dithering using a Bayer matrix of the given size.
+func (pgm *PGM) orderedDitherInto(pbm *PBM, size int) error {
+	matrix, err := bayerMatrix(size)
+	if err != nil {
+		return err
+	}
+	size = len(matrix)
+
+	cells := float64(size * size) // Number of thresholds in the matrix
+	for rowIndex := 0; rowIndex < pgm.height; rowIndex++ {
+		for colIndex := 0; colIndex < pgm.width; colIndex++ {
+			// Each cell of the matrix gives a threshold evenly spread between 0 and max
+			threshold := (float64(matrix[rowIndex%size][colIndex%size]) + 0.5) / cells * float64(pgm.max)
+			pbm.data[rowIndex][colIndex] = float64(pgm.data[rowIndex][colIndex]) < threshold
+		}
+	}
+	return nil
+}
+
+// bayerMatrix builds the Bayer index matrix of the given size (2, 4, 8 or 16, 0 meaning 4).
+func bayerMatrix(size int) ([][]int, error) {
+	if size == 0 {
+		size = 4
+	}
+	if size != 2 && size != 4 && size != 8 && size != 16 {
+		return nil, fmt.Errorf("Unsupported Bayer matrix size: %d", size)
+	}
+
+	matrix := [][]int{{0}}
+	for len(matrix) < size { // Double the matrix until it reaches the requested size
+		n := len(matrix)
+		next := make([][]int, 2*n)
+		for i := range next {
+			next[i] = make([]int, 2*n)
+		}
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				value := 4 * matrix[y][x]
+				next[y][x] = value
+				next[y][x+n] = value + 2
+				next[y+n][x] = value + 3
+				next[y+n][x+n] = value + 1
+			}
+		}
+		matrix = next
+	}
+	return matrix, nil
+}
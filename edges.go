@@ -0,0 +1,242 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// GradientOperator selects the derivative kernels used to compute image gradients.
+type GradientOperator int
+
+const (
+	GradientSobel   GradientOperator = iota // Sobel 3x3 kernels
+	GradientScharr                          // Scharr 3x3 kernels, more accurate orientation
+	GradientPrewitt                         // Prewitt 3x3 kernels
+)
+
+// CannyOptions configures the Canny edge detector.
+type CannyOptions struct {
+	Sigma    float64          // Standard deviation of the Gaussian smoothing (0 means 1.4, negative disables smoothing)
+	Low      float64          // Low hysteresis threshold, as a fraction (0 to 1) of the strongest gradient
+	High     float64          // High hysteresis threshold, as a fraction (0 to 1) of the strongest gradient
+	Operator GradientOperator // Derivative kernels used for the gradient
+}
+
+// Gradient computes the gradient of the PGM image and returns its magnitude, scaled so that the strongest edge is max,
+// and its orientation, mapping angles from 0 to 360 degrees (counter-clockwise from the x axis) to 0..max.
+func (pgm *PGM) Gradient(operator GradientOperator) (magnitude, orientation *PGM, err error) {
+	gx, gy, err := gradientPlanes(uint8PlaneToFloat(pgm.data), operator)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	magnitudes := magnitudePlane(gx, gy)
+	strongest := planeMaximum(magnitudes)
+
+	magnitude, orientation = pgm.emptyLike(), pgm.emptyLike()
+	maxFloat := float64(pgm.max)
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			if strongest > 0 {
+				magnitude.data[y][x] = uint8(math.Round(magnitudes[y][x] / strongest * maxFloat))
+			}
+			angle := math.Atan2(-gy[y][x], gx[y][x]) // Image rows grow downwards
+			if angle < 0 {
+				angle += 2 * math.Pi
+			}
+			orientation.data[y][x] = uint8(math.Round(angle / (2 * math.Pi) * maxFloat))
+		}
+	}
+	return magnitude, orientation, nil
+}
+
+// Gradient computes the gradient of the PPM image after converting it with ToPGM.
+func (ppm *PPM) Gradient(operator GradientOperator) (magnitude, orientation *PGM, err error) {
+	return ppm.ToPGM().Gradient(operator)
+}
+
+// Canny detects the edges of the PGM image with the Canny algorithm: Gaussian smoothing, gradient computation,
+// non-maximum suppression and hysteresis thresholding. Edge pixels are set (black) in the returned PBM image.
+// When Low and High are both 0, the thresholds are derived from the gradient histogram with Otsu's method.
+func (pgm *PGM) Canny(opts CannyOptions) (*PBM, error) {
+	if opts.Low < 0 || opts.High < 0 || opts.Low > opts.High || opts.High > 1 {
+		return nil, fmt.Errorf("Invalid Canny thresholds: low %v, high %v", opts.Low, opts.High)
+	}
+
+	// Gaussian smoothing to reduce the noise
+	plane := uint8PlaneToFloat(pgm.data)
+	sigma := opts.Sigma
+	if sigma == 0 {
+		sigma = 1.4
+	}
+	if sigma > 0 {
+		plane = convolvePlane(plane, GaussianKernel(sigma), EdgeMirror)
+	}
+
+	gx, gy, err := gradientPlanes(plane, opts.Operator)
+	if err != nil {
+		return nil, err
+	}
+	magnitudes := magnitudePlane(gx, gy)
+	strongest := planeMaximum(magnitudes)
+
+	pbm := &PBM{
+		data:        make([][]bool, pgm.height),
+		width:       pgm.width,
+		height:      pgm.height,
+		magicNumber: "P1",
+	}
+	for y := range pbm.data {
+		pbm.data[y] = make([]bool, pgm.width)
+	}
+	if strongest == 0 { // Uniform image: no edge
+		return pbm, nil
+	}
+
+	low, high := opts.Low*strongest, opts.High*strongest
+	if opts.Low == 0 && opts.High == 0 {
+		low, high = autoCannyThresholds(magnitudes, strongest)
+	}
+
+	// Non-maximum suppression: keep only the pixels that are maximal along the gradient direction
+	epsilon := strongest * 1e-9 // Ignore rounding noise when comparing equal magnitudes
+	thin := make([][]float64, pgm.height)
+	for y := 0; y < pgm.height; y++ {
+		thin[y] = make([]float64, pgm.width)
+		for x := 0; x < pgm.width; x++ {
+			value := magnitudes[y][x]
+			if value == 0 {
+				continue
+			}
+			dx, dy := gradientStep(gx[y][x], gy[y][x])
+			before := sampleFloat(magnitudes, x-dx, y-dy, pgm.width, pgm.height, EdgeZero)
+			after := sampleFloat(magnitudes, x+dx, y+dy, pgm.width, pgm.height, EdgeZero)
+			if value >= before-epsilon && value > after+epsilon {
+				thin[y][x] = value
+			}
+		}
+	}
+
+	// Hysteresis: follow the weak edges connected to strong ones
+	var stack []Point
+	for y := 0; y < pgm.height; y++ {
+		for x := 0; x < pgm.width; x++ {
+			if thin[y][x] >= high && !pbm.data[y][x] {
+				pbm.data[y][x] = true
+				stack = append(stack, Point{x, y})
+			}
+			for len(stack) > 0 {
+				current := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				for ny := current.Y - 1; ny <= current.Y+1; ny++ {
+					for nx := current.X - 1; nx <= current.X+1; nx++ {
+						if nx < 0 || nx >= pgm.width || ny < 0 || ny >= pgm.height || pbm.data[ny][nx] {
+							continue
+						}
+						if thin[ny][nx] >= low && thin[ny][nx] > 0 {
+							pbm.data[ny][nx] = true
+							stack = append(stack, Point{nx, ny})
+						}
+					}
+				}
+			}
+		}
+	}
+	return pbm, nil
+}
+
+// Canny detects the edges of the PPM image after converting it with ToPGM.
+func (ppm *PPM) Canny(opts CannyOptions) (*PBM, error) {
+	return ppm.ToPGM().Canny(opts)
+}
+
+// emptyLike returns a black PGM image with the same size, format and maximum value.
+func (pgm *PGM) emptyLike() *PGM {
+	result := &PGM{
+		data:        make([][]uint8, pgm.height),
+		width:       pgm.width,
+		height:      pgm.height,
+		magicNumber: pgm.magicNumber,
+		max:         pgm.max,
+	}
+	for y := range result.data {
+		result.data[y] = make([]uint8, pgm.width)
+	}
+	return result
+}
+
+// gradientPlanes returns the horizontal and vertical derivatives of a plane.
+func gradientPlanes(plane [][]float64, operator GradientOperator) (gx, gy [][]float64, err error) {
+	var kernelX, kernelY *Kernel
+	switch operator {
+	case GradientSobel:
+		kernelX, kernelY = SobelX(), SobelY()
+	case GradientScharr:
+		kernelX, kernelY = ScharrX(), ScharrY()
+	case GradientPrewitt:
+		kernelX, kernelY = PrewittX(), PrewittY()
+	default:
+		return nil, nil, fmt.Errorf("Unsupported gradient operator: %d", operator)
+	}
+	return convolvePlane(plane, kernelX, EdgeClamp), convolvePlane(plane, kernelY, EdgeClamp), nil
+}
+
+// magnitudePlane returns the Euclidean norm of the gradient at each pixel.
+func magnitudePlane(gx, gy [][]float64) [][]float64 {
+	result := make([][]float64, len(gx))
+	for y := range gx {
+		result[y] = make([]float64, len(gx[y]))
+		for x := range gx[y] {
+			result[y][x] = math.Hypot(gx[y][x], gy[y][x])
+		}
+	}
+	return result
+}
+
+// planeMaximum returns the largest value of a plane.
+func planeMaximum(plane [][]float64) float64 {
+	largest := 0.0
+	for _, row := range plane {
+		for _, value := range row {
+			largest = math.Max(largest, value)
+		}
+	}
+	return largest
+}
+
+// gradientStep returns the neighbor offset closest to the gradient direction (one of four directions).
+func gradientStep(gx, gy float64) (int, int) {
+	angle := math.Atan2(gy, gx) * 180 / math.Pi
+	if angle < 0 {
+		angle += 180
+	}
+	switch {
+	case angle < 22.5 || angle >= 157.5:
+		return 1, 0
+	case angle < 67.5:
+		return 1, 1
+	case angle < 112.5:
+		return 0, 1
+	default:
+		return -1, 1
+	}
+}
+
+// autoCannyThresholds derives the hysteresis thresholds from the gradient magnitudes:
+// the high threshold separates edges from background with Otsu's method, the low one is half of it.
+func autoCannyThresholds(magnitudes [][]float64, strongest float64) (float64, float64) {
+	levels := &PGM{
+		data:   make([][]uint8, len(magnitudes)),
+		height: len(magnitudes),
+		max:    255,
+	}
+	for y, row := range magnitudes {
+		levels.data[y] = make([]uint8, len(row))
+		levels.width = len(row)
+		for x, value := range row {
+			levels.data[y][x] = uint8(math.Round(value / strongest * 255))
+		}
+	}
+	high := (float64(levels.OtsuThreshold()) + 0.5) / 255 * strongest
+	return high / 2, high
+}
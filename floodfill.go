@@ -0,0 +1,204 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// FloodFill sets every pixel connected to the seed and having the same value as it to value.
+func (pbm *PBM) FloodFill(seed Point, value bool, connectivity Connectivity) error {
+	mask, err := pbm.FloodFillMask(seed, connectivity)
+	if err != nil {
+		return err
+	}
+	paintMask(mask, func(x, y int) { pbm.data[y][x] = value })
+	return nil
+}
+
+// FloodFillMask returns the region connected to the seed and having the same value as it, as a PBM mask.
+func (pbm *PBM) FloodFillMask(seed Point, connectivity Connectivity) (*PBM, error) {
+	if err := checkSeed(seed, pbm.width, pbm.height, connectivity); err != nil {
+		return nil, err
+	}
+	target := pbm.data[seed.Y][seed.X]
+	return scanlineFill(pbm.width, pbm.height, seed, connectivity, func(x, y int) bool {
+		return pbm.data[y][x] == target
+	}), nil
+}
+
+// FloodFill sets to value every pixel connected to the seed whose gray level differs from the seed one
+// by at most tolerance.
+func (pgm *PGM) FloodFill(seed Point, value uint8, tolerance int, connectivity Connectivity) error {
+	mask, err := pgm.FloodFillMask(seed, tolerance, connectivity)
+	if err != nil {
+		return err
+	}
+	paintMask(mask, func(x, y int) { pgm.data[y][x] = value })
+	return nil
+}
+
+// FloodFillMask returns the region connected to the seed whose gray level differs from the seed one
+// by at most tolerance, as a PBM mask.
+func (pgm *PGM) FloodFillMask(seed Point, tolerance int, connectivity Connectivity) (*PBM, error) {
+	if err := checkSeed(seed, pgm.width, pgm.height, connectivity); err != nil {
+		return nil, err
+	}
+	target := int(pgm.data[seed.Y][seed.X])
+	return scanlineFill(pgm.width, pgm.height, seed, connectivity, func(x, y int) bool {
+		difference := int(pgm.data[y][x]) - target
+		return difference <= tolerance && -difference <= tolerance
+	}), nil
+}
+
+// FloodFill paints with color every pixel connected to the seed whose color is within tolerance
+// (Euclidean distance in RGB) of the seed color.
+func (ppm *PPM) FloodFill(seed Point, color Pixel, tolerance float64, connectivity Connectivity) error {
+	mask, err := ppm.FloodFillMask(seed, tolerance, connectivity)
+	if err != nil {
+		return err
+	}
+	paintMask(mask, func(x, y int) { ppm.data[y][x] = color })
+	return nil
+}
+
+// FloodFillMask returns the region connected to the seed whose color is within tolerance of the seed color,
+// as a PBM mask.
+func (ppm *PPM) FloodFillMask(seed Point, tolerance float64, connectivity Connectivity) (*PBM, error) {
+	if err := checkSeed(seed, ppm.width, ppm.height, connectivity); err != nil {
+		return nil, err
+	}
+	target := ppm.data[seed.Y][seed.X]
+	limit := tolerance * tolerance // Compare squared distances
+	return scanlineFill(ppm.width, ppm.height, seed, connectivity, func(x, y int) bool {
+		pixel := ppm.data[y][x]
+		dr := float64(pixel.R) - float64(target.R)
+		dg := float64(pixel.G) - float64(target.G)
+		db := float64(pixel.B) - float64(target.B)
+		return dr*dr+dg*dg+db*db <= limit
+	}), nil
+}
+
+// checkSeed checks that the seed lies inside the image and that the connectivity is supported.
+func checkSeed(seed Point, width, height int, connectivity Connectivity) error {
+	if seed.X < 0 || seed.X >= width || seed.Y < 0 || seed.Y >= height {
+		return fmt.Errorf("Seed point (%d, %d) is outside of the image", seed.X, seed.Y)
+	}
+	if connectivity != Connectivity4 && connectivity != Connectivity8 {
+		return fmt.Errorf("Unsupported connectivity: %d (must be 4 or 8)", connectivity)
+	}
+	return nil
+}
+
+// paintMask calls paint for every set pixel of the mask.
+func paintMask(mask *PBM, paint func(x, y int)) {
+	for y := 0; y < mask.height; y++ {
+		for x := 0; x < mask.width; x++ {
+			if mask.data[y][x] {
+				paint(x, y)
+			}
+		}
+	}
+}
+
+// scanlineFill returns the mask of the pixels connected to the seed and accepted by match.
+// Whole horizontal spans are filled at once; only the start of each span found on the rows above and below is stacked.
+func scanlineFill(width, height int, seed Point, connectivity Connectivity, match func(x, y int) bool) *PBM {
+	mask := &PBM{
+		data:        newMask(width, height),
+		width:       width,
+		height:      height,
+		magicNumber: "P1",
+	}
+
+	reach := 0 // Extra columns scanned on the neighbor rows, 1 to follow the diagonals
+	if connectivity == Connectivity8 {
+		reach = 1
+	}
+
+	stack := []Point{seed}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if mask.data[current.Y][current.X] || !match(current.X, current.Y) {
+			continue
+		}
+
+		// Extend the span to the left and to the right
+		left, right := current.X, current.X
+		for left > 0 && !mask.data[current.Y][left-1] && match(left-1, current.Y) {
+			left--
+		}
+		for right < width-1 && !mask.data[current.Y][right+1] && match(right+1, current.Y) {
+			right++
+		}
+		for x := left; x <= right; x++ {
+			mask.data[current.Y][x] = true
+		}
+
+		// Look for the spans touching this one on the rows above and below
+		for _, y := range []int{current.Y - 1, current.Y + 1} {
+			if y < 0 || y >= height {
+				continue
+			}
+			inSpan := false
+			for x := max(left-reach, 0); x <= min(right+reach, width-1); x++ {
+				if !mask.data[y][x] && match(x, y) {
+					if !inSpan { // Stack only the first pixel of each span
+						stack = append(stack, Point{x, y})
+						inSpan = true
+					}
+				} else {
+					inSpan = false
+				}
+			}
+		}
+	}
+	return mask
+}
+
+// fillPolygonInterior paints the pixels whose center lies inside the closed outline through the given points,
+// with the even-odd rule. Each image row is crossed with the edges of the polygon and filled between pairs of
+// crossings, so the cost only depends on the image size and the number of points, wherever the points lie.
+// Unlike scanning rows for pixels of the fill color, this ignores whatever was drawn in the image before.
+func (ppm *PPM) fillPolygonInterior(points []Point, color Pixel) {
+	crossings := make([]float64, 0, len(points))
+	for y := 0; y < ppm.height; y++ {
+		center := float64(y)
+		crossings = crossings[:0]
+		for i, start := range points {
+			end := points[(i+1)%len(points)]
+			if start.Y == end.Y {
+				continue // Horizontal edges are covered by the outline
+			}
+			// Half-open span, so that a vertex shared by two edges is counted once
+			low, high := float64(min(start.Y, end.Y)), float64(max(start.Y, end.Y))
+			if center < low || center >= high {
+				continue
+			}
+			ratio := (center - float64(start.Y)) / float64(end.Y-start.Y)
+			crossings = append(crossings, float64(start.X)+ratio*float64(end.X-start.X))
+		}
+		sort.Float64s(crossings)
+
+		for i := 0; i+1 < len(crossings); i += 2 {
+			left := max(int(math.Ceil(crossings[i])), 0)
+			right := min(int(math.Floor(crossings[i+1])), ppm.width-1)
+			for x := left; x <= right; x++ {
+				ppm.data[y][x] = color
+			}
+		}
+	}
+}
+
+// fillCircleInterior paints the pixels closer to the center than radius.
+func (ppm *PPM) fillCircleInterior(center Point, radius int, color Pixel) {
+	for row := max(center.Y-radius, 0); row <= min(center.Y+radius, ppm.height-1); row++ {
+		for col := max(center.X-radius, 0); col <= min(center.X+radius, ppm.width-1); col++ {
+			dx, dy := float64(col-center.X), float64(row-center.Y)
+			if math.Sqrt(dx*dx+dy*dy) < float64(radius) {
+				ppm.data[row][col] = color
+			}
+		}
+	}
+}
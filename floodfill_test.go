@@ -0,0 +1,138 @@
+package Netpbm
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newBlankPPM returns a black width x height PPM image.
+func newBlankPPM(width, height int) *PPM {
+	ppm := &PPM{data: make([][]Pixel, height), width: width, height: height, magicNumber: "P3", max: 255}
+	for y := range ppm.data {
+		ppm.data[y] = make([]Pixel, width)
+	}
+	return ppm
+}
+
+func TestDrawFilledPolygonFarVertices(t *testing.T) {
+	white := Pixel{255, 255, 255}
+
+	// A triangle much larger than the image, covering it entirely
+	ppm := newBlankPPM(10, 10)
+	ppm.DrawFilledTriangle(Point{-1_000_000, -1_000_000}, Point{1_000_000, -1_000_000}, Point{0, 1_000_000}, white)
+	for y := range ppm.data {
+		for x, pixel := range ppm.data[y] {
+			if pixel != white {
+				t.Fatalf("pixel (%d, %d) = %v, want it filled", x, y, pixel)
+			}
+		}
+	}
+
+	// A polygon with one vertex far away: only the half plane x <= y is inside near the image
+	ppm = newBlankPPM(10, 10)
+	ppm.DrawFilledPolygon([]Point{{0, 0}, {1_000_000, 1_000_000}, {0, 1_000_000}}, white)
+	for y := range ppm.data {
+		for x, pixel := range ppm.data[y] {
+			if want := x <= y; (pixel == white) != want {
+				t.Errorf("pixel (%d, %d) filled = %v, want %v", x, y, pixel == white, want)
+			}
+		}
+	}
+}
+
+func TestDrawFilledPolygonConcave(t *testing.T) {
+	white := Pixel{255, 255, 255}
+	ppm := newBlankPPM(9, 9)
+	// A U shape whose notch, between x = 3 and x = 5 above y = 5, must stay empty
+	ppm.DrawFilledPolygon([]Point{{1, 1}, {3, 1}, {3, 5}, {5, 5}, {5, 1}, {7, 1}, {7, 7}, {1, 7}}, white)
+	for _, point := range []Point{{4, 1}, {4, 2}, {4, 4}} {
+		if ppm.data[point.Y][point.X] == white {
+			t.Errorf("pixel %v in the notch is filled", point)
+		}
+	}
+	for _, point := range []Point{{2, 3}, {6, 3}, {4, 6}} {
+		if ppm.data[point.Y][point.X] != white {
+			t.Errorf("pixel %v inside the shape is not filled", point)
+		}
+	}
+	if ppm.data[0][0] == white || ppm.data[8][8] == white {
+		t.Errorf("pixels outside of the shape are filled")
+	}
+}
+
+func TestFloodFillConnectivity(t *testing.T) {
+	// Two set pixels touching only through their corners
+	pbm := &PBM{data: [][]bool{{true, false}, {false, true}}, width: 2, height: 2, magicNumber: "P1"}
+
+	mask, err := pbm.FloodFillMask(Point{0, 0}, Connectivity4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]bool{{true, false}, {false, false}}; !reflect.DeepEqual(mask.data, want) {
+		t.Errorf("4-connected mask = %v, want %v", mask.data, want)
+	}
+
+	mask, err = pbm.FloodFillMask(Point{0, 0}, Connectivity8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]bool{{true, false}, {false, true}}; !reflect.DeepEqual(mask.data, want) {
+		t.Errorf("8-connected mask = %v, want %v", mask.data, want)
+	}
+	if width, height := mask.Size(); width != 2 || height != 2 {
+		t.Errorf("mask size = %dx%d, want 2x2", width, height)
+	}
+
+	if err := pbm.FloodFill(Point{1, 0}, true, Connectivity8); err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]bool{{true, true}, {true, true}}; !reflect.DeepEqual(pbm.data, want) {
+		t.Errorf("filled image = %v, want %v", pbm.data, want)
+	}
+}
+
+func TestFloodFillTolerance(t *testing.T) {
+	tests := []struct {
+		tolerance int
+		want      [][]uint8
+	}{
+		{0, [][]uint8{{9, 12, 20}, {40, 12, 10}}}, // The other 10 is not connected to the seed
+		{2, [][]uint8{{9, 9, 20}, {40, 9, 9}}},
+		{10, [][]uint8{{9, 9, 9}, {40, 9, 9}}},
+	}
+	for _, test := range tests {
+		pgm := &PGM{data: [][]uint8{{10, 12, 20}, {40, 12, 10}}, width: 3, height: 2, magicNumber: "P2", max: 255}
+		if err := pgm.FloodFill(Point{0, 0}, 9, test.tolerance, Connectivity4); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(pgm.data, test.want) {
+			t.Errorf("tolerance %d: image = %v, want %v", test.tolerance, pgm.data, test.want)
+		}
+	}
+
+	ppm := newBlankPPM(3, 1)
+	ppm.data[0][1] = Pixel{3, 4, 0} // At distance 5 from black
+	ppm.data[0][2] = Pixel{0, 0, 9}
+	red := Pixel{255, 0, 0}
+	if err := ppm.FloodFill(Point{0, 0}, red, 5, Connectivity4); err != nil {
+		t.Fatal(err)
+	}
+	if want := []Pixel{red, red, {0, 0, 9}}; !reflect.DeepEqual(ppm.data[0], want) {
+		t.Errorf("PPM image = %v, want %v", ppm.data[0], want)
+	}
+}
+
+func TestFloodFillInvalidArguments(t *testing.T) {
+	pgm := newTestPGM()
+	for _, seed := range []Point{{-1, 0}, {3, 0}, {0, 2}} {
+		if _, err := pgm.FloodFillMask(seed, 0, Connectivity4); err == nil {
+			t.Errorf("seed %v outside of the 3x2 image accepted", seed)
+		}
+	}
+	if err := newTestPPM().FloodFill(Point{0, 0}, Pixel{}, 0, Connectivity(6)); err == nil {
+		t.Errorf("connectivity 6 accepted")
+	}
+	if err := newTestPBM().FloodFill(Point{5, 5}, true, Connectivity8); err == nil {
+		t.Errorf("seed outside of the PBM image accepted")
+	}
+}
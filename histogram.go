@@ -0,0 +1,277 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// Histogram returns the number of pixels for each gray level, from 0 to max.
+func (pgm *PGM) Histogram() []int {
+	return planeHistogram(pgm.data, pgm.max)
+}
+
+// CumulativeHistogram returns, for each gray level from 0 to max, the number of pixels less than or equal to it.
+func (pgm *PGM) CumulativeHistogram() []int {
+	return cumulate(pgm.Histogram())
+}
+
+// Histogram returns the number of pixels for each value, from 0 to max, of the red, green and blue channels.
+func (ppm *PPM) Histogram() (red, green, blue []int) {
+	return planeHistogram(ppm.channelPlane(0), ppm.max),
+		planeHistogram(ppm.channelPlane(1), ppm.max),
+		planeHistogram(ppm.channelPlane(2), ppm.max)
+}
+
+// CumulativeHistogram returns the cumulative histograms of the red, green and blue channels.
+func (ppm *PPM) CumulativeHistogram() (red, green, blue []int) {
+	red, green, blue = ppm.Histogram()
+	return cumulate(red), cumulate(green), cumulate(blue)
+}
+
+// Equalize spreads the gray levels of the PGM image so that its cumulative histogram is as linear as possible.
+func (pgm *PGM) Equalize() {
+	equalizePlane(pgm.data, pgm.max)
+}
+
+// Equalize equalizes the histogram of each channel of the PPM image independently.
+func (ppm *PPM) Equalize() {
+	for channel := 0; channel < 3; channel++ {
+		plane := ppm.channelPlane(channel)
+		equalizePlane(plane, ppm.max)
+		ppm.setChannelPlane(channel, plane)
+	}
+}
+
+// CLAHE applies contrast-limited adaptive histogram equalization to the PGM image.
+// The image is divided into tiles of tileWidth x tileHeight pixels, each one equalized with its histogram
+// clipped at clipLimit times the average bin count; the results are bilinearly interpolated between tiles.
+func (pgm *PGM) CLAHE(tileWidth, tileHeight int, clipLimit float64) error {
+	if tileWidth < 1 || tileHeight < 1 {
+		return fmt.Errorf("Invalid CLAHE tile size: %dx%d", tileWidth, tileHeight)
+	}
+	clahePlane(pgm.data, pgm.max, tileWidth, tileHeight, clipLimit)
+	return nil
+}
+
+// CLAHE applies contrast-limited adaptive histogram equalization to each channel of the PPM image.
+func (ppm *PPM) CLAHE(tileWidth, tileHeight int, clipLimit float64) error {
+	if tileWidth < 1 || tileHeight < 1 {
+		return fmt.Errorf("Invalid CLAHE tile size: %dx%d", tileWidth, tileHeight)
+	}
+	for channel := 0; channel < 3; channel++ {
+		plane := ppm.channelPlane(channel)
+		clahePlane(plane, ppm.max, tileWidth, tileHeight, clipLimit)
+		ppm.setChannelPlane(channel, plane)
+	}
+	return nil
+}
+
+// MatchHistogram remaps the gray levels of the PGM image so that its histogram matches the one of the reference image.
+func (pgm *PGM) MatchHistogram(reference *PGM) {
+	matchPlane(pgm.data, pgm.max, reference.data, reference.max)
+}
+
+// MatchHistogram remaps each channel of the PPM image so that its histogram matches the same channel of the reference image.
+func (ppm *PPM) MatchHistogram(reference *PPM) {
+	for channel := 0; channel < 3; channel++ {
+		plane := ppm.channelPlane(channel)
+		matchPlane(plane, ppm.max, reference.channelPlane(channel), reference.max)
+		ppm.setChannelPlane(channel, plane)
+	}
+}
+
+// channelPlane returns a copy of the red (0), green (1) or blue (2) channel of the image.
+func (ppm *PPM) channelPlane(channel int) [][]uint8 {
+	plane := make([][]uint8, ppm.height)
+	for y := 0; y < ppm.height; y++ {
+		plane[y] = make([]uint8, ppm.width)
+		for x := 0; x < ppm.width; x++ {
+			plane[y][x] = channelValue(ppm.data[y][x], channel)
+		}
+	}
+	return plane
+}
+
+// setChannelPlane writes a plane back into the red (0), green (1) or blue (2) channel of the image.
+func (ppm *PPM) setChannelPlane(channel int, plane [][]uint8) {
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			switch channel {
+			case 0:
+				ppm.data[y][x].R = plane[y][x]
+			case 1:
+				ppm.data[y][x].G = plane[y][x]
+			default:
+				ppm.data[y][x].B = plane[y][x]
+			}
+		}
+	}
+}
+
+// planeHistogram counts the values of a plane from 0 to max (values above max are counted as max).
+func planeHistogram(plane [][]uint8, maxValue int) []int {
+	histogram := make([]int, maxValue+1)
+	for _, row := range plane {
+		for _, value := range row {
+			histogram[min(int(value), maxValue)]++
+		}
+	}
+	return histogram
+}
+
+// cumulate returns the running sum of a histogram.
+func cumulate(histogram []int) []int {
+	cumulative := make([]int, len(histogram))
+	total := 0
+	for level, count := range histogram {
+		total += count
+		cumulative[level] = total
+	}
+	return cumulative
+}
+
+// equalizationMap builds the lookup table equalizing a histogram over 0..max.
+func equalizationMap(histogram []int, maxValue int) []uint8 {
+	cumulative := cumulate(histogram)
+	total := cumulative[len(cumulative)-1]
+
+	// The first non-empty level is mapped to 0 so that the full range is used
+	cdfMin := 0
+	for _, value := range cumulative {
+		if value > 0 {
+			cdfMin = value
+			break
+		}
+	}
+
+	lookup := make([]uint8, len(histogram))
+	if total == cdfMin { // Single gray level: nothing to spread
+		for level := range lookup {
+			lookup[level] = uint8(level)
+		}
+		return lookup
+	}
+	for level, value := range cumulative {
+		mapped := math.Round(float64(value-cdfMin) / float64(total-cdfMin) * float64(maxValue))
+		lookup[level] = uint8(math.Max(mapped, 0))
+	}
+	return lookup
+}
+
+// equalizePlane equalizes the histogram of a plane in place.
+func equalizePlane(plane [][]uint8, maxValue int) {
+	lookup := equalizationMap(planeHistogram(plane, maxValue), maxValue)
+	for _, row := range plane {
+		for x, value := range row {
+			row[x] = lookup[min(int(value), maxValue)]
+		}
+	}
+}
+
+// clahePlane applies contrast-limited adaptive histogram equalization to a plane in place.
+func clahePlane(plane [][]uint8, maxValue, tileWidth, tileHeight int, clipLimit float64) {
+	height := len(plane)
+	if height == 0 {
+		return
+	}
+	width := len(plane[0])
+	tilesX := (width + tileWidth - 1) / tileWidth    // Number of tile columns
+	tilesY := (height + tileHeight - 1) / tileHeight // Number of tile rows
+
+	// Build the clipped equalization map of every tile
+	maps := make([][][]uint8, tilesY)
+	for tileY := 0; tileY < tilesY; tileY++ {
+		maps[tileY] = make([][]uint8, tilesX)
+		for tileX := 0; tileX < tilesX; tileX++ {
+			histogram := make([]int, maxValue+1)
+			pixels := 0
+			for y := tileY * tileHeight; y < min((tileY+1)*tileHeight, height); y++ {
+				for x := tileX * tileWidth; x < min((tileX+1)*tileWidth, width); x++ {
+					histogram[min(int(plane[y][x]), maxValue)]++
+					pixels++
+				}
+			}
+
+			if clipLimit > 0 { // Clip the histogram and redistribute the excess evenly over all bins
+				limit := max(int(clipLimit*float64(pixels)/float64(maxValue+1)), 1)
+				excess := 0
+				for level, count := range histogram {
+					if count > limit {
+						excess += count - limit
+						histogram[level] = limit
+					}
+				}
+				share, remainder := excess/(maxValue+1), excess%(maxValue+1)
+				for level := range histogram {
+					histogram[level] += share
+					if level < remainder {
+						histogram[level]++
+					}
+				}
+			}
+			maps[tileY][tileX] = tileEqualizationMap(histogram, maxValue)
+		}
+	}
+
+	// Interpolate between the maps of the four tiles whose centers surround each pixel
+	for y := 0; y < height; y++ {
+		tileYf := (float64(y)+0.5)/float64(tileHeight) - 0.5 // Position relative to the tile centers
+		y0 := int(math.Floor(tileYf))
+		fy := tileYf - float64(y0)
+		y1 := min(y0+1, tilesY-1)
+		y0 = min(max(y0, 0), tilesY-1)
+		for x := 0; x < width; x++ {
+			tileXf := (float64(x)+0.5)/float64(tileWidth) - 0.5
+			x0 := int(math.Floor(tileXf))
+			fx := tileXf - float64(x0)
+			x1 := min(x0+1, tilesX-1)
+			x0 = min(max(x0, 0), tilesX-1)
+
+			value := min(int(plane[y][x]), maxValue)
+			top := (1-fx)*float64(maps[y0][x0][value]) + fx*float64(maps[y0][x1][value])
+			bottom := (1-fx)*float64(maps[y1][x0][value]) + fx*float64(maps[y1][x1][value])
+			plane[y][x] = uint8(math.Round((1-fy)*top + fy*bottom))
+		}
+	}
+}
+
+// tileEqualizationMap builds the lookup table of a CLAHE tile: value = cdf * max / pixels.
+func tileEqualizationMap(histogram []int, maxValue int) []uint8 {
+	cumulative := cumulate(histogram)
+	total := cumulative[len(cumulative)-1]
+	lookup := make([]uint8, len(histogram))
+	for level, value := range cumulative {
+		if total > 0 {
+			lookup[level] = uint8(math.Round(float64(value) * float64(maxValue) / float64(total)))
+		}
+	}
+	return lookup
+}
+
+// matchPlane remaps a plane so that its cumulative histogram follows the one of a reference plane.
+func matchPlane(plane [][]uint8, maxValue int, reference [][]uint8, referenceMax int) {
+	source := cumulate(planeHistogram(plane, maxValue))
+	target := cumulate(planeHistogram(reference, referenceMax))
+	sourceTotal, targetTotal := source[len(source)-1], target[len(target)-1]
+	if sourceTotal == 0 || targetTotal == 0 {
+		return
+	}
+
+	lookup := make([]uint8, maxValue+1)
+	targetLevel := 0
+	for level := 0; level <= maxValue; level++ {
+		// Find the first reference level whose cumulative proportion reaches the source one
+		proportion := float64(source[level]) / float64(sourceTotal)
+		for targetLevel < referenceMax && float64(target[targetLevel])/float64(targetTotal) < proportion {
+			targetLevel++
+		}
+		// Express the reference level in the range of this plane
+		lookup[level] = uint8(math.Round(float64(targetLevel) * float64(maxValue) / float64(referenceMax)))
+	}
+
+	for _, row := range plane {
+		for x, value := range row {
+			row[x] = lookup[min(int(value), maxValue)]
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// LineSegment is a detected line, given by its two end points.
+type LineSegment struct {
+	Start, End Point // End points of the segment
+	Votes      int   // Number of edge pixels that voted for the line
+}
+
+// Circle is a detected circle.
+type Circle struct {
+	Center Point   // Center of the circle
+	Radius int     // Radius in pixels
+	Score  float64 // Fraction (0 to 1) of the circumference covered by edge pixels
+}
+
+// HoughLineOptions configures the line Hough transforms.
+type HoughLineOptions struct {
+	RhoStep   float64 // Distance resolution of the accumulator in pixels (0 means 1)
+	ThetaStep float64 // Angle resolution of the accumulator in radians (0 means 1 degree)
+	Threshold int     // Minimum number of votes for a line
+	MaxLines  int     // Maximum number of lines returned, strongest first (0 means no limit)
+	MinLength int     // Probabilistic transform only: minimum length of a segment in pixels
+	MaxGap    int     // Probabilistic transform only: largest gap in pixels bridged inside a segment
+	Seed      int64   // Probabilistic transform only: seed of the random order in which edge pixels are visited
+}
+
+// HoughCircleOptions configures the circle Hough transform.
+type HoughCircleOptions struct {
+	MinRadius, MaxRadius int     // Range of the radii searched
+	Threshold            float64 // Minimum fraction (0 to 1) of the circumference covered by edge pixels (0 means 0.5)
+	MinDistance          int     // Minimum distance between two centers (0 means MinRadius)
+	MaxCircles           int     // Maximum number of circles returned, best first (0 means no limit)
+}
+
+// houghSpace is the (theta, rho) accumulator of the line Hough transform.
+type houghSpace struct {
+	votes     [][]int   // Votes for each angle and distance
+	cos, sin  []float64 // Cosine and sine of each angle
+	thetaStep float64   // Angle between two consecutive rows of votes
+	rhoStep   float64   // Distance between two consecutive columns of votes
+	rhoOffset int       // Column of the distance 0
+}
+
+// newHoughSpace allocates an empty accumulator for an image of the given size.
+func newHoughSpace(width, height int, rhoStep, thetaStep float64) (*houghSpace, error) {
+	if rhoStep == 0 {
+		rhoStep = 1
+	}
+	if thetaStep == 0 {
+		thetaStep = math.Pi / 180
+	}
+	if rhoStep < 0 || thetaStep < 0 || thetaStep > math.Pi {
+		return nil, fmt.Errorf("Invalid Hough resolution: rho step %v, theta step %v", rhoStep, thetaStep)
+	}
+
+	thetas := int(math.Round(math.Pi / thetaStep))
+	rhoOffset := int(math.Ceil(math.Hypot(float64(width), float64(height)) / rhoStep))
+	space := &houghSpace{
+		votes:     make([][]int, thetas),
+		cos:       make([]float64, thetas),
+		sin:       make([]float64, thetas),
+		thetaStep: thetaStep,
+		rhoStep:   rhoStep,
+		rhoOffset: rhoOffset,
+	}
+	for t := range space.votes {
+		space.votes[t] = make([]int, 2*rhoOffset+1)
+		space.cos[t] = math.Cos(float64(t) * thetaStep)
+		space.sin[t] = math.Sin(float64(t) * thetaStep)
+	}
+	return space, nil
+}
+
+// vote adds (or removes, with a negative weight) the votes of the point for every line through it.
+// It returns the angle index and the votes of the best cell touched.
+func (space *houghSpace) vote(point Point, weight int) (bestTheta, bestVotes int) {
+	for t := range space.votes {
+		r := space.rhoIndex(point, t)
+		space.votes[t][r] += weight
+		if space.votes[t][r] > bestVotes {
+			bestTheta, bestVotes = t, space.votes[t][r]
+		}
+	}
+	return bestTheta, bestVotes
+}
+
+// rhoIndex returns the column of the line of angle index t through the point.
+func (space *houghSpace) rhoIndex(point Point, t int) int {
+	rho := float64(point.X)*space.cos[t] + float64(point.Y)*space.sin[t]
+	return int(math.Round(rho/space.rhoStep)) + space.rhoOffset
+}
+
+// HoughLines finds the straight lines through the set pixels of an edge map with the standard Hough transform.
+// Each line is returned as the segment joining the points where it crosses the border of the image.
+func (pbm *PBM) HoughLines(opts HoughLineOptions) ([]LineSegment, error) {
+	space, err := newHoughSpace(pbm.width, pbm.height, opts.RhoStep, opts.ThetaStep)
+	if err != nil {
+		return nil, err
+	}
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if pbm.data[y][x] {
+				space.vote(Point{x, y}, 1)
+			}
+		}
+	}
+
+	// Peaks: cells reaching the threshold and not smaller than their neighbors
+	type peak struct{ theta, rho, votes int }
+	var peaks []peak
+	thetas, rhos := len(space.votes), len(space.votes[0])
+	for t := 0; t < thetas; t++ {
+		for r := 0; r < rhos; r++ {
+			votes := space.votes[t][r]
+			if votes == 0 || votes < opts.Threshold {
+				continue
+			}
+			isPeak := true
+			for dt := -1; dt <= 1 && isPeak; dt++ {
+				for dr := -1; dr <= 1; dr++ {
+					nt, nr := t+dt, r+dr
+					if (dt == 0 && dr == 0) || nt < 0 || nt >= thetas || nr < 0 || nr >= rhos {
+						continue
+					}
+					// Ties are broken towards the first cell so that flat peaks give a single line
+					if space.votes[nt][nr] > votes || (space.votes[nt][nr] == votes && (dt < 0 || (dt == 0 && dr < 0))) {
+						isPeak = false
+						break
+					}
+				}
+			}
+			if isPeak {
+				peaks = append(peaks, peak{t, r, votes})
+			}
+		}
+	}
+	sort.SliceStable(peaks, func(i, j int) bool { return peaks[i].votes > peaks[j].votes })
+	if opts.MaxLines > 0 && len(peaks) > opts.MaxLines {
+		peaks = peaks[:opts.MaxLines]
+	}
+
+	lines := make([]LineSegment, 0, len(peaks))
+	for _, p := range peaks {
+		rho := float64(p.rho-space.rhoOffset) * space.rhoStep
+		start, end := lineAcross(rho, space.cos[p.theta], space.sin[p.theta], pbm.width, pbm.height)
+		lines = append(lines, LineSegment{Start: start, End: end, Votes: p.votes})
+	}
+	return lines, nil
+}
+
+// HoughLinesP finds the line segments of an edge map with the progressive probabilistic Hough transform:
+// edge pixels vote in random order, and as soon as a cell reaches the threshold the segment through the pixel
+// is followed along the line, bridging gaps up to MaxGap. Its pixels are then withdrawn from the accumulator.
+func (pbm *PBM) HoughLinesP(opts HoughLineOptions) ([]LineSegment, error) {
+	space, err := newHoughSpace(pbm.width, pbm.height, opts.RhoStep, opts.ThetaStep)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MinLength < 0 || opts.MaxGap < 0 {
+		return nil, fmt.Errorf("Invalid segment constraints: minimum length %d, maximum gap %d", opts.MinLength, opts.MaxGap)
+	}
+
+	remaining := newMask(pbm.width, pbm.height) // Edge pixels not yet part of a segment
+	voted := newMask(pbm.width, pbm.height)     // Edge pixels whose votes are in the accumulator
+	var points []Point
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if pbm.data[y][x] {
+				remaining[y][x] = true
+				points = append(points, Point{x, y})
+			}
+		}
+	}
+	random := rand.New(rand.NewSource(opts.Seed))
+	random.Shuffle(len(points), func(i, j int) { points[i], points[j] = points[j], points[i] })
+
+	var segments []LineSegment
+	for _, point := range points {
+		if !remaining[point.Y][point.X] {
+			continue
+		}
+		theta, votes := space.vote(point, 1)
+		voted[point.Y][point.X] = true
+		if votes < max(opts.Threshold, 1) {
+			continue
+		}
+
+		// Follow the line through the point in both directions
+		dx, dy := -space.sin[theta], space.cos[theta] // Direction of the line
+		step := 1 / math.Max(math.Abs(dx), math.Abs(dy))
+		dx, dy = dx*step, dy*step // One pixel along the major axis per step
+		across := Point{0, 1}     // Step across the line, along its minor axis
+		if math.Abs(dy) > math.Abs(dx) {
+			across = Point{1, 0}
+		}
+		at := func(i int) (int, int) {
+			return int(math.Round(float64(point.X) + float64(i)*dx)), int(math.Round(float64(point.Y) + float64(i)*dy))
+		}
+		// Edge pixels left at step i, tolerating one pixel across the line for rasterization differences
+		edgesAt := func(i int) []Point {
+			x, y := at(i)
+			var found []Point
+			for _, offset := range []int{0, -1, 1} {
+				nx, ny := x+offset*across.X, y+offset*across.Y
+				if nx >= 0 && nx < pbm.width && ny >= 0 && ny < pbm.height && remaining[ny][nx] {
+					found = append(found, Point{nx, ny})
+				}
+			}
+			return found
+		}
+
+		var reach [2]int // Steps from the point to the last edge pixel found on each side
+		for side, sign := range []int{1, -1} {
+			gap := 0
+			for i := 1; ; i++ {
+				x, y := at(sign * i)
+				if x < 0 || x >= pbm.width || y < 0 || y >= pbm.height {
+					break
+				}
+				if len(edgesAt(sign*i)) > 0 {
+					reach[side] = i
+					gap = 0
+				} else if gap++; gap > opts.MaxGap {
+					break
+				}
+			}
+		}
+		startX, startY := at(-reach[1])
+		endX, endY := at(reach[0])
+		long := max(abs(endX-startX), abs(endY-startY)) >= opts.MinLength
+		if !long {
+			continue
+		}
+
+		// Withdraw the pixels of the segment, and their votes, so that they do not form other lines
+		for i := -reach[1]; i <= reach[0]; i++ {
+			for _, edge := range edgesAt(i) {
+				if voted[edge.Y][edge.X] {
+					space.vote(edge, -1)
+					voted[edge.Y][edge.X] = false
+				}
+				remaining[edge.Y][edge.X] = false
+			}
+		}
+		segments = append(segments, LineSegment{Start: Point{startX, startY}, End: Point{endX, endY}, Votes: votes})
+		if opts.MaxLines > 0 && len(segments) == opts.MaxLines {
+			break
+		}
+	}
+	return segments, nil
+}
+
+// HoughCircles finds the circles drawn by the set pixels of an edge map. Every edge pixel votes for the centers
+// of all the circles of the searched radii passing through it; the centers covered by enough edge pixels are kept,
+// best first, skipping those closer than MinDistance to a better one.
+func (pbm *PBM) HoughCircles(opts HoughCircleOptions) ([]Circle, error) {
+	if opts.MinRadius < 1 || opts.MaxRadius < opts.MinRadius {
+		return nil, fmt.Errorf("Invalid radius range: %d to %d", opts.MinRadius, opts.MaxRadius)
+	}
+	threshold := opts.Threshold
+	if threshold == 0 {
+		threshold = 0.5
+	}
+	if threshold < 0 || threshold > 1 {
+		return nil, fmt.Errorf("Invalid circle threshold: %v (must be between 0 and 1)", threshold)
+	}
+	minDistance := opts.MinDistance
+	if minDistance == 0 {
+		minDistance = opts.MinRadius
+	}
+
+	// One accumulator of centers per radius
+	radii := opts.MaxRadius - opts.MinRadius + 1
+	accumulators := make([][]int32, radii)
+	outlines := make([][]Point, radii)
+	for i := range accumulators {
+		accumulators[i] = make([]int32, pbm.width*pbm.height)
+		outlines[i] = circleOffsets(opts.MinRadius + i)
+	}
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if !pbm.data[y][x] {
+				continue
+			}
+			for i, outline := range outlines {
+				for _, offset := range outline {
+					cx, cy := x+offset.X, y+offset.Y
+					if cx >= 0 && cx < pbm.width && cy >= 0 && cy < pbm.height {
+						accumulators[i][cy*pbm.width+cx]++
+					}
+				}
+			}
+		}
+	}
+
+	// Candidates: local maxima over the position and the radius reaching the threshold
+	var candidates []Circle
+	for i, accumulator := range accumulators {
+		for y := 0; y < pbm.height; y++ {
+			for x := 0; x < pbm.width; x++ {
+				votes := accumulator[y*pbm.width+x]
+				score := float64(votes) / float64(len(outlines[i]))
+				if votes == 0 || score < threshold {
+					continue
+				}
+				isPeak := true
+				for di := -1; di <= 1 && isPeak; di++ {
+					for ny := y - 1; ny <= y+1 && isPeak; ny++ {
+						for nx := x - 1; nx <= x+1; nx++ {
+							ni := i + di
+							if ni < 0 || ni >= radii || nx < 0 || nx >= pbm.width || ny < 0 || ny >= pbm.height {
+								continue
+							}
+							if float64(accumulators[ni][ny*pbm.width+nx])/float64(len(outlines[ni])) > score {
+								isPeak = false
+								break
+							}
+						}
+					}
+				}
+				if isPeak {
+					candidates = append(candidates, Circle{Center: Point{x, y}, Radius: opts.MinRadius + i, Score: score})
+				}
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+
+	var circles []Circle
+	for _, candidate := range candidates {
+		tooClose := false
+		for _, circle := range circles {
+			dx, dy := candidate.Center.X-circle.Center.X, candidate.Center.Y-circle.Center.Y
+			if dx*dx+dy*dy < minDistance*minDistance {
+				tooClose = true
+				break
+			}
+		}
+		if tooClose {
+			continue
+		}
+		circles = append(circles, candidate)
+		if opts.MaxCircles > 0 && len(circles) == opts.MaxCircles {
+			break
+		}
+	}
+	return circles, nil
+}
+
+// DrawLineSegments draws detected lines onto the PPM image.
+func (ppm *PPM) DrawLineSegments(segments []LineSegment, color Pixel) {
+	for _, segment := range segments {
+		ppm.DrawLine(segment.Start, segment.End, color)
+	}
+}
+
+// DrawCircles draws detected circles onto the PPM image.
+func (ppm *PPM) DrawCircles(circles []Circle, color Pixel) {
+	for _, circle := range circles {
+		ppm.DrawCircle(circle.Center, circle.Radius, color)
+	}
+}
+
+// lineAcross returns the points where the line x*cos + y*sin = rho crosses the border of the image,
+// stepping along its major axis so that the segment covers the whole image.
+func lineAcross(rho, cos, sin float64, width, height int) (Point, Point) {
+	if math.Abs(sin) >= math.Abs(cos) { // Closer to horizontal: from the left border to the right one
+		left := rho / sin
+		right := (rho - float64(width-1)*cos) / sin
+		return Point{0, int(math.Round(left))}, Point{width - 1, int(math.Round(right))}
+	}
+	top := rho / cos
+	bottom := (rho - float64(height-1)*sin) / cos
+	return Point{int(math.Round(top)), 0}, Point{int(math.Round(bottom)), height - 1}
+}
+
+// circleOffsets returns the distinct pixel offsets of a circle of the given radius.
+func circleOffsets(radius int) []Point {
+	seen := make(map[Point]bool)
+	var offsets []Point
+	steps := int(math.Ceil(2 * math.Pi * float64(radius) * 2)) // Two samples per pixel of circumference
+	for i := 0; i < steps; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(steps)
+		offset := Point{int(math.Round(float64(radius) * math.Cos(angle))), int(math.Round(float64(radius) * math.Sin(angle)))}
+		if !seen[offset] {
+			seen[offset] = true
+			offsets = append(offsets, offset)
+		}
+	}
+	return offsets
+}
+
+// abs returns the absolute value of an integer.
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
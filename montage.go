@@ -0,0 +1,213 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// Image is implemented by the PBM, PGM and PPM images only.
+type Image interface {
+	Size() (int, int)
+	netpbmImage() // Keeps other types, such as Paletted, from satisfying the interface
+}
+
+func (pbm *PBM) netpbmImage() {}
+func (pgm *PGM) netpbmImage() {}
+func (ppm *PPM) netpbmImage() {}
+
+// MontageOptions controls the layout of a contact sheet.
+type MontageOptions struct {
+	Columns      int      // Number of tiles per row, 0 for a roughly square grid
+	TileWidth    int      // Width of a tile, 0 for the width of the widest image
+	TileHeight   int      // Height of a tile, 0 for the height of the tallest image
+	Gutter       int      // Space between the tiles and around the sheet
+	Background   Pixel    // Color of the gutters and of the tile areas not covered by an image
+	Captions     []string // Optional caption written under each tile, in the order of the images
+	CaptionColor Pixel    // Color of the captions
+	CaptionScale int      // Size of a font pixel in the captions, 0 for 1
+}
+
+// Montage lays the images out in a grid and returns the resulting contact sheet. PBM and PGM images are promoted
+// to color, and images larger than a tile are shrunk to fit in it keeping their aspect ratio. Each image is
+// centered in its tile, and its caption is centered under it, truncated to the tile width.
+func Montage(images []Image, options MontageOptions) (*PPM, error) {
+	if len(images) == 0 {
+		return nil, fmt.Errorf("No image to lay out")
+	}
+	if options.Columns < 0 || options.TileWidth < 0 || options.TileHeight < 0 || options.Gutter < 0 {
+		return nil, fmt.Errorf("Invalid montage options: %+v", options)
+	}
+
+	tiles := make([]*PPM, len(images))
+	tileWidth, tileHeight := options.TileWidth, options.TileHeight
+	for i, image := range images {
+		tile, err := promoteToPPM(image)
+		if err != nil {
+			return nil, err
+		}
+		tiles[i] = tile
+		if options.TileWidth == 0 {
+			tileWidth = max(tileWidth, tile.width)
+		}
+		if options.TileHeight == 0 {
+			tileHeight = max(tileHeight, tile.height)
+		}
+	}
+
+	columns := options.Columns
+	if columns == 0 {
+		columns = int(math.Ceil(math.Sqrt(float64(len(tiles)))))
+	}
+	columns = min(columns, len(tiles))
+	rows := (len(tiles) + columns - 1) / columns
+
+	// Reserve room under the tiles when at least one caption is given
+	scale := max(options.CaptionScale, 1)
+	captionHeight := 0
+	for _, caption := range options.Captions {
+		if caption != "" {
+			captionHeight = (glyphHeight + 2*glyphSpace) * scale
+			break
+		}
+	}
+
+	gutter := options.Gutter
+	sheet := &PPM{
+		width:       columns*tileWidth + (columns+1)*gutter,
+		height:      rows*(tileHeight+captionHeight) + (rows+1)*gutter,
+		magicNumber: "P3",
+		max:         255,
+	}
+	sheet.data = make([][]Pixel, sheet.height)
+	for y := range sheet.data {
+		sheet.data[y] = make([]Pixel, sheet.width)
+		for x := range sheet.data[y] {
+			sheet.data[y][x] = options.Background
+		}
+	}
+
+	for i, tile := range tiles {
+		if tile.width > tileWidth || tile.height > tileHeight {
+			if err := tile.Resize(tileWidth, tileHeight, ResampleArea, AspectFit); err != nil {
+				return nil, err
+			}
+		}
+		left := gutter + (i%columns)*(tileWidth+gutter)
+		top := gutter + (i/columns)*(tileHeight+captionHeight+gutter)
+		offsetX, offsetY := left+(tileWidth-tile.width)/2, top+(tileHeight-tile.height)/2
+		for y := 0; y < tile.height; y++ {
+			copy(sheet.data[offsetY+y][offsetX:offsetX+tile.width], tile.data[y])
+		}
+
+		if i < len(options.Captions) && options.Captions[i] != "" {
+			caption := []rune(options.Captions[i])
+			fitting := (tileWidth + glyphSpace*scale) / ((glyphWidth + glyphSpace) * scale)
+			if len(caption) > fitting {
+				caption = caption[:fitting]
+			}
+			width, _ := MeasureText(string(caption), scale)
+			sheet.DrawText(Point{left + (tileWidth-width)/2, top + tileHeight + glyphSpace*scale}, string(caption), options.CaptionColor, scale)
+		}
+	}
+	return sheet, nil
+}
+
+// Slice cuts the PBM sprite sheet into tileWidth x tileHeight tiles separated by spacing pixels, and returns them
+// row by row. Partial tiles at the right and bottom edges are dropped.
+func (pbm *PBM) Slice(tileWidth, tileHeight, spacing int) ([]*PBM, error) {
+	rectangles, err := sliceRectangles(pbm.width, pbm.height, tileWidth, tileHeight, spacing)
+	if err != nil {
+		return nil, err
+	}
+	tiles := make([]*PBM, len(rectangles))
+	for i, rectangle := range rectangles {
+		data, _ := cropPlane(pbm.data, pbm.width, pbm.height, rectangle)
+		tiles[i] = &PBM{data: data, width: tileWidth, height: tileHeight, magicNumber: pbm.magicNumber}
+	}
+	return tiles, nil
+}
+
+// Slice cuts the PGM sprite sheet into tileWidth x tileHeight tiles separated by spacing pixels, and returns them
+// row by row. Partial tiles at the right and bottom edges are dropped.
+func (pgm *PGM) Slice(tileWidth, tileHeight, spacing int) ([]*PGM, error) {
+	rectangles, err := sliceRectangles(pgm.width, pgm.height, tileWidth, tileHeight, spacing)
+	if err != nil {
+		return nil, err
+	}
+	tiles := make([]*PGM, len(rectangles))
+	for i, rectangle := range rectangles {
+		data, _ := cropPlane(pgm.data, pgm.width, pgm.height, rectangle)
+		tiles[i] = &PGM{data: data, width: tileWidth, height: tileHeight, magicNumber: pgm.magicNumber, max: pgm.max}
+	}
+	return tiles, nil
+}
+
+// Slice cuts the PPM sprite sheet into tileWidth x tileHeight tiles separated by spacing pixels, and returns them
+// row by row. Partial tiles at the right and bottom edges are dropped.
+func (ppm *PPM) Slice(tileWidth, tileHeight, spacing int) ([]*PPM, error) {
+	rectangles, err := sliceRectangles(ppm.width, ppm.height, tileWidth, tileHeight, spacing)
+	if err != nil {
+		return nil, err
+	}
+	tiles := make([]*PPM, len(rectangles))
+	for i, rectangle := range rectangles {
+		data, _ := cropPlane(ppm.data, ppm.width, ppm.height, rectangle)
+		tiles[i] = &PPM{data: data, width: tileWidth, height: tileHeight, magicNumber: ppm.magicNumber, max: ppm.max}
+	}
+	return tiles, nil
+}
+
+// sliceRectangles returns the rectangles of the whole tiles of a sprite sheet, row by row.
+func sliceRectangles(width, height, tileWidth, tileHeight, spacing int) ([]Rectangle, error) {
+	if tileWidth < 1 || tileHeight < 1 || spacing < 0 {
+		return nil, fmt.Errorf("Invalid tile size %dx%d with spacing %d", tileWidth, tileHeight, spacing)
+	}
+	if tileWidth > width || tileHeight > height {
+		return nil, fmt.Errorf("Tiles of %dx%d do not fit in the %dx%d image", tileWidth, tileHeight, width, height)
+	}
+	var rectangles []Rectangle
+	for top := 0; top+tileHeight <= height; top += tileHeight + spacing {
+		for left := 0; left+tileWidth <= width; left += tileWidth + spacing {
+			rectangles = append(rectangles, Rectangle{TopLeft: Point{left, top}, Width: tileWidth, Height: tileHeight})
+		}
+	}
+	return rectangles, nil
+}
+
+// promoteToPPM returns a copy of the image as a PPM image with a maximum value of 255.
+// Set PBM pixels become black and gray levels are rescaled to 0..255.
+func promoteToPPM(image Image) (*PPM, error) {
+	width, height := image.Size()
+	ppm := &PPM{data: make([][]Pixel, height), width: width, height: height, magicNumber: "P3", max: 255}
+	for y := range ppm.data {
+		ppm.data[y] = make([]Pixel, width)
+	}
+
+	switch source := image.(type) {
+	case *PBM:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				if !source.data[y][x] {
+					ppm.data[y][x] = Pixel{255, 255, 255}
+				}
+			}
+		}
+	case *PGM:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				gray := scaleTo255(source.data[y][x], source.max)
+				ppm.data[y][x] = Pixel{gray, gray, gray}
+			}
+		}
+	case *PPM:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				pixel := source.data[y][x]
+				ppm.data[y][x] = Pixel{scaleTo255(pixel.R, source.max), scaleTo255(pixel.G, source.max), scaleTo255(pixel.B, source.max)}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported image type: %T", image)
+	}
+	return ppm, nil
+}
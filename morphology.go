@@ -0,0 +1,399 @@
+package Netpbm
+
+import "fmt"
+
+// ElementCell is the role of a cell of a structuring element.
+type ElementCell int8
+
+const (
+	CellIgnore     ElementCell = iota // The cell is not part of the element
+	CellForeground                    // The cell must be set (the only cells used by erosion and dilation)
+	CellBackground                    // The cell must be unset (used by hit-or-miss only)
+)
+
+// StructuringElement is the neighborhood shape used by the morphological operations.
+type StructuringElement struct {
+	cells            [][]ElementCell // Role of each cell, indexed as cells[y][x]
+	width, height    int             // Size of the element
+	anchorX, anchorY int             // Cell placed over the pixel being computed
+}
+
+// SquareElement returns a (2*radius+1) square structuring element.
+func SquareElement(radius int) *StructuringElement {
+	return shapeElement(radius, func(dx, dy int) bool { return true })
+}
+
+// CrossElement returns a cross-shaped structuring element whose arms are radius pixels long.
+func CrossElement(radius int) *StructuringElement {
+	return shapeElement(radius, func(dx, dy int) bool { return dx == 0 || dy == 0 })
+}
+
+// DiskElement returns a disk-shaped structuring element of the given radius.
+func DiskElement(radius int) *StructuringElement {
+	return shapeElement(radius, func(dx, dy int) bool { return dx*dx+dy*dy <= radius*radius })
+}
+
+// ElementFromPBM returns a structuring element made of the set pixels of a (small) PBM image, anchored at its center.
+func ElementFromPBM(pbm *PBM) *StructuringElement {
+	element := newElement(pbm.width, pbm.height)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if pbm.data[y][x] {
+				element.cells[y][x] = CellForeground
+			}
+		}
+	}
+	return element
+}
+
+// NewStructuringElement builds a structuring element from rows of characters, anchored at its center:
+// '1' marks a foreground cell, '0' a background cell (hit-or-miss) and any other character an ignored cell.
+func NewStructuringElement(rows ...string) (*StructuringElement, error) {
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return nil, fmt.Errorf("Invalid structuring element: no cells")
+	}
+	element := newElement(len(rows[0]), len(rows))
+	for y, row := range rows {
+		if len(row) != element.width {
+			return nil, fmt.Errorf("Invalid structuring element: row %d has %d cells instead of %d", y, len(row), element.width)
+		}
+		for x, cell := range row {
+			switch cell {
+			case '1':
+				element.cells[y][x] = CellForeground
+			case '0':
+				element.cells[y][x] = CellBackground
+			}
+		}
+	}
+	return element, nil
+}
+
+// SetAnchor moves the anchor of the structuring element to the cell (x, y).
+func (se *StructuringElement) SetAnchor(x, y int) {
+	se.anchorX, se.anchorY = x, y
+}
+
+// Size returns the width and height of the structuring element.
+func (se *StructuringElement) Size() (int, int) {
+	return se.width, se.height
+}
+
+// rotated returns the structuring element rotated by 90 degrees clockwise around its anchor.
+func (se *StructuringElement) rotated() *StructuringElement {
+	element := newElement(se.height, se.width)
+	for y := 0; y < se.height; y++ {
+		for x := 0; x < se.width; x++ {
+			element.cells[x][se.height-y-1] = se.cells[y][x]
+		}
+	}
+	element.anchorX, element.anchorY = se.height-se.anchorY-1, se.anchorX
+	return element
+}
+
+// Erode removes the set pixels of the PBM image whose neighborhood is not entirely covered by the element.
+// Pixels outside of the image count as unset.
+func (pbm *PBM) Erode(se *StructuringElement) {
+	pbm.data = erodeMask(pbm.data, pbm.width, pbm.height, se)
+}
+
+// Dilate sets every pixel of the PBM image reached by the element placed on a set pixel.
+func (pbm *PBM) Dilate(se *StructuringElement) {
+	pbm.data = dilateMask(pbm.data, pbm.width, pbm.height, se)
+}
+
+// Open erodes then dilates the PBM image, removing the objects smaller than the element.
+func (pbm *PBM) Open(se *StructuringElement) {
+	pbm.Erode(se)
+	pbm.Dilate(se)
+}
+
+// Close dilates then erodes the PBM image, filling the holes smaller than the element.
+func (pbm *PBM) Close(se *StructuringElement) {
+	pbm.Dilate(se)
+	pbm.Erode(se)
+}
+
+// MorphologicalGradient keeps the pixels of the PBM image set by dilation but not by erosion (the object outlines).
+func (pbm *PBM) MorphologicalGradient(se *StructuringElement) {
+	dilated := dilateMask(pbm.data, pbm.width, pbm.height, se)
+	eroded := erodeMask(pbm.data, pbm.width, pbm.height, se)
+	pbm.data = combineMasks(dilated, eroded, func(a, b bool) bool { return a && !b })
+}
+
+// TopHat keeps the set pixels of the PBM image removed by an opening (the details smaller than the element).
+func (pbm *PBM) TopHat(se *StructuringElement) {
+	opened := dilateMask(erodeMask(pbm.data, pbm.width, pbm.height, se), pbm.width, pbm.height, se)
+	pbm.data = combineMasks(pbm.data, opened, func(a, b bool) bool { return a && !b })
+}
+
+// BlackHat keeps the unset pixels of the PBM image filled by a closing (the holes smaller than the element).
+func (pbm *PBM) BlackHat(se *StructuringElement) {
+	closed := erodeMask(dilateMask(pbm.data, pbm.width, pbm.height, se), pbm.width, pbm.height, se)
+	pbm.data = combineMasks(closed, pbm.data, func(a, b bool) bool { return a && !b })
+}
+
+// HitOrMiss keeps only the pixels of the PBM image where the foreground cells of the element fall on set pixels
+// and its background cells on unset pixels. Pixels outside of the image count as unset.
+func (pbm *PBM) HitOrMiss(se *StructuringElement) {
+	pbm.data = hitOrMissMask(pbm.data, pbm.width, pbm.height, se)
+}
+
+// Thin repeatedly removes the border pixels of the objects of the PBM image with the eight rotations of the classic
+// thinning elements, until the image no longer changes or the given number of iterations is reached (0 means no limit).
+func (pbm *PBM) Thin(iterations int) {
+	edge, _ := NewStructuringElement("000", ".1.", "111")
+	corner, _ := NewStructuringElement(".00", "110", ".1.")
+	var elements []*StructuringElement
+	for i := 0; i < 4; i++ { // The four rotations of both elements
+		elements = append(elements, edge, corner)
+		edge, corner = edge.rotated(), corner.rotated()
+	}
+
+	for iteration := 0; iterations == 0 || iteration < iterations; iteration++ {
+		changed := false
+		for _, element := range elements {
+			hits := hitOrMissMask(pbm.data, pbm.width, pbm.height, element)
+			for y := 0; y < pbm.height; y++ {
+				for x := 0; x < pbm.width; x++ {
+					if hits[y][x] { // Remove the matched pixels
+						pbm.data[y][x] = false
+						changed = true
+					}
+				}
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+}
+
+// Skeletonize reduces the objects of the PBM image to one pixel wide skeletons with the Zhang–Suen algorithm.
+func (pbm *PBM) Skeletonize() {
+	at := func(x, y int) int { // Neighbor value, pixels outside of the image being unset
+		if x < 0 || x >= pbm.width || y < 0 || y >= pbm.height || !pbm.data[y][x] {
+			return 0
+		}
+		return 1
+	}
+
+	for {
+		changed := false
+		for step := 0; step < 2; step++ {
+			var removed []Point
+			for y := 0; y < pbm.height; y++ {
+				for x := 0; x < pbm.width; x++ {
+					if !pbm.data[y][x] {
+						continue
+					}
+					// Neighbors P2 to P9, clockwise from the top
+					neighbors := [8]int{at(x, y-1), at(x+1, y-1), at(x+1, y), at(x+1, y+1), at(x, y+1), at(x-1, y+1), at(x-1, y), at(x-1, y-1)}
+					count, transitions := 0, 0
+					for i, value := range neighbors {
+						count += value
+						if value == 0 && neighbors[(i+1)%8] == 1 {
+							transitions++
+						}
+					}
+					if count < 2 || count > 6 || transitions != 1 {
+						continue
+					}
+					p2, p4, p6, p8 := neighbors[0], neighbors[2], neighbors[4], neighbors[6]
+					if step == 0 && p2*p4*p6 == 0 && p4*p6*p8 == 0 {
+						removed = append(removed, Point{x, y})
+					}
+					if step == 1 && p2*p4*p8 == 0 && p2*p6*p8 == 0 {
+						removed = append(removed, Point{x, y})
+					}
+				}
+			}
+			for _, point := range removed { // Pixels are removed after each sub-iteration
+				pbm.data[point.Y][point.X] = false
+			}
+			changed = changed || len(removed) > 0
+		}
+		if !changed {
+			return
+		}
+	}
+}
+
+// Erode replaces each pixel of the PGM image by the minimum over the foreground cells of the flat element.
+// Pixels outside of the image are ignored.
+func (pgm *PGM) Erode(se *StructuringElement) {
+	pgm.data = grayMorphology(pgm.data, pgm.width, pgm.height, se, false)
+}
+
+// Dilate replaces each pixel of the PGM image by the maximum over the foreground cells of the flat element.
+func (pgm *PGM) Dilate(se *StructuringElement) {
+	pgm.data = grayMorphology(pgm.data, pgm.width, pgm.height, se, true)
+}
+
+// Open erodes then dilates the PGM image, removing the bright details smaller than the element.
+func (pgm *PGM) Open(se *StructuringElement) {
+	pgm.Erode(se)
+	pgm.Dilate(se)
+}
+
+// Close dilates then erodes the PGM image, removing the dark details smaller than the element.
+func (pgm *PGM) Close(se *StructuringElement) {
+	pgm.Dilate(se)
+	pgm.Erode(se)
+}
+
+// newElement creates an empty structuring element anchored at its center.
+func newElement(width, height int) *StructuringElement {
+	element := &StructuringElement{
+		cells:   make([][]ElementCell, height),
+		width:   width,
+		height:  height,
+		anchorX: width / 2,
+		anchorY: height / 2,
+	}
+	for y := range element.cells {
+		element.cells[y] = make([]ElementCell, width)
+	}
+	return element
+}
+
+// shapeElement builds a (2*radius+1) element whose foreground cells satisfy the predicate.
+func shapeElement(radius int, inside func(dx, dy int) bool) *StructuringElement {
+	radius = max(radius, 0)
+	element := newElement(2*radius+1, 2*radius+1)
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			if inside(dx, dy) {
+				element.cells[dy+radius][dx+radius] = CellForeground
+			}
+		}
+	}
+	return element
+}
+
+// maskAt reads a mask pixel, pixels outside of it being unset.
+func maskAt(mask [][]bool, x, y, width, height int) bool {
+	return x >= 0 && x < width && y >= 0 && y < height && mask[y][x]
+}
+
+// erodeMask returns the erosion of a mask by the foreground cells of the element.
+func erodeMask(mask [][]bool, width, height int, se *StructuringElement) [][]bool {
+	result := newMask(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			result[y][x] = true
+			for ey := 0; ey < se.height && result[y][x]; ey++ {
+				for ex := 0; ex < se.width; ex++ {
+					if se.cells[ey][ex] == CellForeground && !maskAt(mask, x+ex-se.anchorX, y+ey-se.anchorY, width, height) {
+						result[y][x] = false
+						break
+					}
+				}
+			}
+		}
+	}
+	return result
+}
+
+// dilateMask returns the dilation of a mask by the foreground cells of the element.
+func dilateMask(mask [][]bool, width, height int, se *StructuringElement) [][]bool {
+	result := newMask(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if !mask[y][x] {
+				continue
+			}
+			// Stamp the element on every set pixel
+			for ey := 0; ey < se.height; ey++ {
+				for ex := 0; ex < se.width; ex++ {
+					tx, ty := x+ex-se.anchorX, y+ey-se.anchorY
+					if se.cells[ey][ex] == CellForeground && tx >= 0 && tx < width && ty >= 0 && ty < height {
+						result[ty][tx] = true
+					}
+				}
+			}
+		}
+	}
+	return result
+}
+
+// hitOrMissMask returns the pixels where the foreground cells of the element match set pixels and its
+// background cells match unset pixels.
+func hitOrMissMask(mask [][]bool, width, height int, se *StructuringElement) [][]bool {
+	result := newMask(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			match := true
+			for ey := 0; ey < se.height && match; ey++ {
+				for ex := 0; ex < se.width; ex++ {
+					cell := se.cells[ey][ex]
+					if cell == CellIgnore {
+						continue
+					}
+					if maskAt(mask, x+ex-se.anchorX, y+ey-se.anchorY, width, height) != (cell == CellForeground) {
+						match = false
+						break
+					}
+				}
+			}
+			result[y][x] = match
+		}
+	}
+	return result
+}
+
+// combineMasks combines two masks pixel by pixel.
+func combineMasks(first, second [][]bool, combine func(a, b bool) bool) [][]bool {
+	result := make([][]bool, len(first))
+	for y := range first {
+		result[y] = make([]bool, len(first[y]))
+		for x := range first[y] {
+			result[y][x] = combine(first[y][x], second[y][x])
+		}
+	}
+	return result
+}
+
+// newMask creates an unset mask of the given size.
+func newMask(width, height int) [][]bool {
+	mask := make([][]bool, height)
+	for y := range mask {
+		mask[y] = make([]bool, width)
+	}
+	return mask
+}
+
+// grayMorphology returns the flat grayscale erosion (minimum) or dilation (maximum) of a plane.
+func grayMorphology(plane [][]uint8, width, height int, se *StructuringElement, dilate bool) [][]uint8 {
+	result := make([][]uint8, height)
+	for y := 0; y < height; y++ {
+		result[y] = make([]uint8, width)
+		for x := 0; x < width; x++ {
+			best, found := uint8(0), false
+			for ey := 0; ey < se.height; ey++ {
+				for ex := 0; ex < se.width; ex++ {
+					if se.cells[ey][ex] != CellForeground {
+						continue
+					}
+					// The dilation uses the reflected element so that it matches the binary dilation
+					sx, sy := x+ex-se.anchorX, y+ey-se.anchorY
+					if dilate {
+						sx, sy = x-(ex-se.anchorX), y-(ey-se.anchorY)
+					}
+					if sx < 0 || sx >= width || sy < 0 || sy >= height {
+						continue
+					}
+					value := plane[sy][sx]
+					if !found || (dilate && value > best) || (!dilate && value < best) {
+						best, found = value, true
+					}
+				}
+			}
+			if !found { // No cell of the element falls inside the image
+				best = plane[y][x]
+			}
+			result[y][x] = best
+		}
+	}
+	return result
+}
@@ -0,0 +1,314 @@
+package Netpbm
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"math"
+	"os"
+	"sort"
+)
+
+// Paletted is a struct representing an indexed image: each pixel is an index into a palette of at most 256 colors.
+type Paletted struct {
+	data          [][]uint8 // Palette index of each pixel
+	width, height int       // Width and height of the image
+	palette       []Pixel   // Colors referenced by the indices
+	max           int       // Maximum color value of the palette entries
+	magicNumber   string    // Format identifier used when converting back to PPM ("P3" or "P6")
+}
+
+// NewPaletted creates a paletted image of the given size whose pixels all use the first palette entry.
+func NewPaletted(width, height int, palette []Pixel, max int) (*Paletted, error) {
+	if len(palette) < 1 || len(palette) > 256 { // Indices are stored on one byte
+		return nil, fmt.Errorf("Invalid palette size: %d (must be between 1 and 256)", len(palette))
+	}
+	if max < 1 || max > 255 {
+		return nil, fmt.Errorf("Error: The maximum must be between 1 and 255.")
+	}
+
+	paletted := &Paletted{
+		data:        make([][]uint8, height),
+		width:       width,
+		height:      height,
+		palette:     append([]Pixel(nil), palette...),
+		max:         max,
+		magicNumber: "P3",
+	}
+	for y := range paletted.data {
+		paletted.data[y] = make([]uint8, width)
+	}
+	return paletted, nil
+}
+
+// Size returns the width and height of the image.
+func (p *Paletted) Size() (int, int) {
+	return p.width, p.height
+}
+
+// At returns the palette index at (x, y).
+func (p *Paletted) At(x, y int) uint8 {
+	return p.data[y][x]
+}
+
+// Set sets the palette index at (x, y). The index must refer to an entry of the palette.
+func (p *Paletted) Set(x, y int, index uint8) error {
+	if err := p.checkPaletteIndex(int(index)); err != nil {
+		return err
+	}
+	p.data[y][x] = index
+	return nil
+}
+
+// ColorAt returns the color (Pixel) at (x, y).
+func (p *Paletted) ColorAt(x, y int) Pixel {
+	return p.palette[p.data[y][x]]
+}
+
+// Palette returns a copy of the palette of the image.
+func (p *Paletted) Palette() []Pixel {
+	return append([]Pixel(nil), p.palette...)
+}
+
+// SetPaletteEntry replaces the color of a palette entry, changing every pixel that uses it.
+func (p *Paletted) SetPaletteEntry(index int, color Pixel) error {
+	if err := p.checkPaletteIndex(index); err != nil {
+		return err
+	}
+	p.palette[index] = color
+	return nil
+}
+
+// checkPaletteIndex checks that index refers to an entry of the palette.
+func (p *Paletted) checkPaletteIndex(index int) error {
+	if index < 0 || index >= len(p.palette) {
+		return fmt.Errorf("Invalid palette index %d: the palette has %d colors", index, len(p.palette))
+	}
+	return nil
+}
+
+// ToPaletted converts the PPM image to a paletted image without any loss.
+// It returns an error if the image uses more than 256 distinct colors; use Quantize first in that case.
+func (ppm *PPM) ToPaletted() (*Paletted, error) {
+	paletted := &Paletted{
+		data:        make([][]uint8, ppm.height),
+		width:       ppm.width,
+		height:      ppm.height,
+		max:         ppm.max,
+		magicNumber: ppm.magicNumber,
+	}
+
+	indices := make(map[Pixel]uint8) // Palette index of each color met so far
+	for y := 0; y < ppm.height; y++ {
+		paletted.data[y] = make([]uint8, ppm.width)
+		for x := 0; x < ppm.width; x++ {
+			pixel := ppm.data[y][x]
+			index, ok := indices[pixel]
+			if !ok { // New color: add it to the palette
+				if len(paletted.palette) == 256 {
+					return nil, fmt.Errorf("Too many colors for a paletted image: more than 256")
+				}
+				index = uint8(len(paletted.palette))
+				indices[pixel] = index
+				paletted.palette = append(paletted.palette, pixel)
+			}
+			paletted.data[y][x] = index
+		}
+	}
+	return paletted, nil
+}
+
+// ToPaletted returns the quantization result as a paletted image.
+func (q *Quantized) ToPaletted() *Paletted {
+	paletted := &Paletted{
+		data:        make([][]uint8, len(q.Indices)),
+		height:      len(q.Indices),
+		palette:     append([]Pixel(nil), q.Palette...),
+		max:         255,
+		magicNumber: "P3",
+	}
+	if q.Image != nil {
+		paletted.width, paletted.max, paletted.magicNumber = q.Image.width, q.Image.max, q.Image.magicNumber
+	} else if len(q.Indices) > 0 {
+		paletted.width = len(q.Indices[0])
+	}
+	for y, row := range q.Indices {
+		paletted.data[y] = append([]uint8(nil), row...)
+	}
+	return paletted
+}
+
+// ToPPM converts the paletted image to a PPM image, with the magic number of the image it was made from.
+func (p *Paletted) ToPPM() *PPM {
+	ppm := &PPM{
+		data:        make([][]Pixel, p.height),
+		width:       p.width,
+		height:      p.height,
+		magicNumber: p.magicNumber,
+		max:         p.max,
+	}
+	if ppm.magicNumber == "" {
+		ppm.magicNumber = "P3"
+	}
+	for y := 0; y < p.height; y++ {
+		ppm.data[y] = make([]Pixel, p.width)
+		for x := 0; x < p.width; x++ {
+			ppm.data[y][x] = p.palette[p.data[y][x]] // Look up the color of each index
+		}
+	}
+	return ppm
+}
+
+// SwapPaletteEntries exchanges two palette entries and updates the indices so that the image is unchanged.
+func (p *Paletted) SwapPaletteEntries(i, j int) error {
+	if err := p.checkPaletteIndex(i); err != nil {
+		return err
+	}
+	if err := p.checkPaletteIndex(j); err != nil {
+		return err
+	}
+	p.palette[i], p.palette[j] = p.palette[j], p.palette[i]
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			switch int(p.data[y][x]) {
+			case i:
+				p.data[y][x] = uint8(j)
+			case j:
+				p.data[y][x] = uint8(i)
+			}
+		}
+	}
+	return nil
+}
+
+// Remap replaces every index i of the image by mapping[i], for instance to merge several entries into one.
+// The palette itself is left unchanged.
+func (p *Paletted) Remap(mapping []uint8) error {
+	if len(mapping) < len(p.palette) {
+		return fmt.Errorf("Invalid mapping: %d entries for a palette of %d colors", len(mapping), len(p.palette))
+	}
+	for _, index := range mapping[:len(p.palette)] {
+		if int(index) >= len(p.palette) {
+			return fmt.Errorf("Invalid mapping: index %d is out of the palette", index)
+		}
+	}
+
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			p.data[y][x] = mapping[p.data[y][x]]
+		}
+	}
+	return nil
+}
+
+// SortPaletteByLuminance orders the palette from darkest to lightest and updates the indices so that the image is unchanged.
+func (p *Paletted) SortPaletteByLuminance() {
+	order := make([]int, len(p.palette)) // order[newIndex] = oldIndex
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return luminance(p.palette[order[a]]) < luminance(p.palette[order[b]])
+	})
+
+	mapping := make([]uint8, len(p.palette)) // mapping[oldIndex] = newIndex
+	sorted := make([]Pixel, len(p.palette))
+	for newIndex, oldIndex := range order {
+		mapping[oldIndex] = uint8(newIndex)
+		sorted[newIndex] = p.palette[oldIndex]
+	}
+
+	p.Remap(mapping)
+	p.palette = sorted
+}
+
+// luminance returns the Rec. 709 relative luminance of a color.
+func luminance(pixel Pixel) float64 {
+	return 0.2126*float64(pixel.R) + 0.7152*float64(pixel.G) + 0.0722*float64(pixel.B)
+}
+
+// ToImage converts the paletted image to an image.Paletted from the standard library.
+// The palette colors are scaled from 0..max to 0..255.
+func (p *Paletted) ToImage() *image.Paletted {
+	palette := make(color.Palette, len(p.palette))
+	for i, entry := range p.palette {
+		palette[i] = color.RGBA{R: scaleTo255(entry.R, p.max), G: scaleTo255(entry.G, p.max), B: scaleTo255(entry.B, p.max), A: 255}
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, p.width, p.height), palette)
+	for y := 0; y < p.height; y++ {
+		copy(img.Pix[y*img.Stride:y*img.Stride+p.width], p.data[y]) // Indices are stored row by row
+	}
+	return img
+}
+
+// PalettedFromImage converts an image.Paletted from the standard library to a paletted image with a maximum value of 255.
+func PalettedFromImage(img *image.Paletted) (*Paletted, error) {
+	if len(img.Palette) < 1 || len(img.Palette) > 256 {
+		return nil, fmt.Errorf("Invalid palette size: %d (must be between 1 and 256)", len(img.Palette))
+	}
+
+	bounds := img.Bounds()
+	paletted := &Paletted{
+		data:        make([][]uint8, bounds.Dy()),
+		width:       bounds.Dx(),
+		height:      bounds.Dy(),
+		palette:     make([]Pixel, len(img.Palette)),
+		max:         255,
+		magicNumber: "P3",
+	}
+	for i, entry := range img.Palette {
+		r, g, b, _ := entry.RGBA() // 16-bit channels
+		paletted.palette[i] = Pixel{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8)}
+	}
+	for y := 0; y < paletted.height; y++ {
+		paletted.data[y] = make([]uint8, paletted.width)
+		for x := 0; x < paletted.width; x++ {
+			index := img.ColorIndexAt(bounds.Min.X+x, bounds.Min.Y+y)
+			if int(index) >= len(img.Palette) {
+				return nil, fmt.Errorf("Invalid palette index %d at (%d, %d)", index, x, y)
+			}
+			paletted.data[y][x] = index
+		}
+	}
+	return paletted, nil
+}
+
+// SaveGIF saves the paletted image to a GIF file.
+func (p *Paletted) SaveGIF(filename string) error {
+	file, err := os.Create(filename) // Create or open the file for writing
+	if err != nil {                  // Check for file creation errors
+		return err
+	}
+	defer file.Close() // Close the file when the function completes
+
+	return gif.Encode(file, p.ToImage(), &gif.Options{NumColors: len(p.palette)})
+}
+
+// ReadGIF reads the first frame of a GIF file and returns it as a paletted image.
+func ReadGIF(filename string) (*Paletted, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, err := gif.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	frame, ok := img.(*image.Paletted)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported GIF image type: %T", img)
+	}
+	return PalettedFromImage(frame)
+}
+
+// scaleTo255 rescales a channel value from 0..max to 0..255.
+func scaleTo255(value uint8, max int) uint8 {
+	if max == 255 || max <= 0 {
+		return value
+	}
+	return uint8(math.Round(math.Min(float64(value)*255/float64(max), 255)))
+}
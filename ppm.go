@@ -219,14 +219,14 @@ func (ppm *PPM) SetMaxValue(newMaxValue uint8) {
 
 // Rotate90CW rotates the PPM image 90 degrees clockwise.
 func (ppm *PPM) Rotate90CW() {
-	newData := make([][]Pixel, ppm.height)
+	newData := make([][]Pixel, ppm.width) // The rotated image has one row per original column
 	for i := range newData {
-		newData[i] = make([]Pixel, ppm.width)
+		newData[i] = make([]Pixel, ppm.height)
 	}
 
 	// Rotate pixel values by 90 degrees clockwise.
-	for i := 0; i < ppm.height; i++ {
-		for j := 0; j < ppm.width; j++ {
+	for i := 0; i < ppm.width; i++ {
+		for j := 0; j < ppm.height; j++ {
 			newData[i][j] = ppm.data[ppm.height-j-1][i]
 		}
 
@@ -390,35 +390,15 @@ func (ppm *PPM) DrawFilledRectangle(topLeft Point, width, height int, fillPixel
 	// Draw the outline of the rectangle using DrawRectangle method
 	ppm.DrawRectangle(topLeft, width, height, fillPixel)
 
-	// Iterate through each row of the PPM image
-	for row := 0; row < ppm.height; row++ {
-		var coloredPixelPositions []int
-		var coloredPixelCount int
-
-		// Iterate through each column of the current row
-		for col := 0; col < ppm.width; col++ {
-			// Check if the current pixel has the specified fill color
-			if ppm.data[row][col] == fillPixel {
-				coloredPixelCount++
-				coloredPixelPositions = append(coloredPixelPositions, col)
-			}
-		}
-
-		// Fill the space between the leftmost and rightmost colored pixels in the current row
-		if coloredPixelCount > 1 {
-			leftmostCol := coloredPixelPositions[0] + 1
-			rightmostCol := coloredPixelPositions[len(coloredPixelPositions)-1]
+	// Use the same bounds as DrawRectangle
+	left, top := max(topLeft.X, 0), max(topLeft.Y, 0)
+	right := left + min(width, ppm.width-left)
+	bottom := top + min(height, ppm.height-top)
 
-			for col := leftmostCol; col < rightmostCol; col++ {
-				ppm.data[row][col] = fillPixel
-			}
-		}
-
-		// If the specified width or height is greater than the image dimensions, fill the entire row
-		if height > ppm.height && width > ppm.width {
-			for col := 0; col < ppm.width; col++ {
-				ppm.data[row][col] = fillPixel
-			}
+	// Fill every pixel inside the bounds, whatever its previous color
+	for row := top; row <= min(bottom, ppm.height-1); row++ {
+		for col := left; col <= min(right, ppm.width-1); col++ {
+			ppm.data[row][col] = fillPixel
 		}
 	}
 }
@@ -444,11 +424,17 @@ func (ppm *PPM) DrawCircle(center Point, radius int, color Pixel) {
 		}
 	}
 
-	// Mark key points on the circle boundary by setting their colors
-	ppm.Set(center.X-(radius-1), center.Y, color)
-	ppm.Set(center.X+(radius-1), center.Y, color)
-	ppm.Set(center.X, center.Y+(radius-1), color)
-	ppm.Set(center.X, center.Y-(radius-1), color)
+	// Mark key points on the circle boundary by setting their colors, skipping those outside of the image
+	for _, point := range []Point{
+		{center.X - (radius - 1), center.Y},
+		{center.X + (radius - 1), center.Y},
+		{center.X, center.Y + (radius - 1)},
+		{center.X, center.Y - (radius - 1)},
+	} {
+		if point.X >= 0 && point.X < ppm.width && point.Y >= 0 && point.Y < ppm.height {
+			ppm.Set(point.X, point.Y, color)
+		}
+	}
 }
 
 // DrawFilledCircle draws a filled circle.
@@ -456,29 +442,8 @@ func (ppm *PPM) DrawFilledCircle(center Point, radius int, color Pixel) {
 	// Draw the circle outline
 	ppm.DrawCircle(center, radius, color)
 
-	for row := 0; row < ppm.height; row++ {
-		var coloredPixelPositions []int
-		var coloredPixelCount int
-
-		// Iterate through each column of the current row
-		for col := 0; col < ppm.width; col++ {
-			// Check if the current pixel has the specified fill color
-			if ppm.data[row][col] == color {
-				coloredPixelCount++
-				coloredPixelPositions = append(coloredPixelPositions, col)
-			}
-		}
-
-		// Fill the space between the leftmost and rightmost colored pixels in the current row
-		if coloredPixelCount > 1 {
-			leftmostCol := coloredPixelPositions[0] + 1
-			rightmostCol := coloredPixelPositions[len(coloredPixelPositions)-1]
-
-			for col := leftmostCol; col < rightmostCol; col++ {
-				ppm.data[row][col] = color
-			}
-		}
-	}
+	// Fill the pixels inside the circle
+	ppm.fillCircleInterior(center, radius, color)
 }
 
 // DrawTriangle draws a triangle.
@@ -493,29 +458,8 @@ func (ppm *PPM) DrawFilledTriangle(p1, p2, p3 Point, color Pixel) {
 	// Draw the triangle outline
 	ppm.DrawTriangle(p1, p2, p3, color)
 
-	for row := 0; row < ppm.height; row++ {
-		var coloredPixelPositions []int
-		var coloredPixelCount int
-
-		// Iterate through each column of the current row
-		for col := 0; col < ppm.width; col++ {
-			// Check if the current pixel has the specified fill color
-			if ppm.data[row][col] == color {
-				coloredPixelCount++
-				coloredPixelPositions = append(coloredPixelPositions, col)
-			}
-		}
-
-		// Fill the space between the leftmost and rightmost colored pixels in the current row
-		if coloredPixelCount > 1 {
-			leftmostCol := coloredPixelPositions[0] + 1
-			rightmostCol := coloredPixelPositions[len(coloredPixelPositions)-1]
-
-			for col := leftmostCol; col < rightmostCol; col++ {
-				ppm.data[row][col] = color
-			}
-		}
-	}
+	// Fill the pixels enclosed by the outline
+	ppm.fillPolygonInterior([]Point{p1, p2, p3}, color)
 }
 
 // DrawPolygon draws a polygon.
@@ -535,29 +479,8 @@ func (ppm *PPM) DrawFilledPolygon(points []Point, color Pixel) {
 	// Draw the polygon outline
 	ppm.DrawPolygon(points, color)
 
-	for row := 0; row < ppm.height; row++ {
-		var coloredPixelPositions []int
-		var coloredPixelCount int
-
-		// Iterate through each column of the current row
-		for col := 0; col < ppm.width; col++ {
-			// Check if the current pixel has the specified fill color
-			if ppm.data[row][col] == color {
-				coloredPixelCount++
-				coloredPixelPositions = append(coloredPixelPositions, col)
-			}
-		}
-
-		// Fill the space between the leftmost and rightmost colored pixels in the current row
-		if coloredPixelCount > 1 {
-			leftmostCol := coloredPixelPositions[0] + 1
-			rightmostCol := coloredPixelPositions[len(coloredPixelPositions)-1]
-
-			for col := leftmostCol; col < rightmostCol; col++ {
-				ppm.data[row][col] = color
-			}
-		}
-	}
+	// Fill the pixels enclosed by the outline
+	ppm.fillPolygonInterior(points, color)
 }
 
 // DrawSierpinskiTriangle draws a Sierpinski triangle.
@@ -0,0 +1,499 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// QuantizeMethod selects the algorithm used to build a reduced palette.
+type QuantizeMethod int
+
+const (
+	QuantizeMedianCut QuantizeMethod = iota // Recursive median cut of the color cube
+	QuantizeOctree                          // Octree color reduction
+	QuantizeKMeans                          // k-means clustering seeded with the median cut palette
+)
+
+// Quantized is the result of a color quantization: a palette, the palette index of each pixel and the rebuilt image.
+type Quantized struct {
+	Palette []Pixel   // Colors of the palette (at most 256)
+	Indices [][]uint8 // Palette index of each pixel, indexed as Indices[y][x]
+	Image   *PPM      // New PPM image where each pixel is replaced by its palette color
+}
+
+// colorCount is a distinct color of an image along with the number of pixels using it.
+type colorCount struct {
+	color Pixel
+	count int
+}
+
+// Quantize reduces the PPM image to at most the given number of colors (1 to 256) with the chosen method.
+// The pixels are then mapped to the palette, with error diffusion or ordered dithering unless dither is DitherNone.
+func (ppm *PPM) Quantize(colors int, method QuantizeMethod, dither DitherMethod) (*Quantized, error) {
+	if colors < 1 || colors > 256 { // The index map stores one byte per pixel
+		return nil, fmt.Errorf("Invalid number of colors: %d (must be between 1 and 256)", colors)
+	}
+
+	var palette []Pixel
+	switch method {
+	case QuantizeMedianCut:
+		palette = medianCut(ppm.distinctColors(), colors)
+	case QuantizeOctree:
+		palette = ppm.octreePalette(colors)
+	case QuantizeKMeans:
+		distinct := ppm.distinctColors()
+		palette = kMeans(distinct, medianCut(distinct, colors), 16)
+	default:
+		return nil, fmt.Errorf("Unsupported quantization method: %d", method)
+	}
+
+	return ppm.MapToPalette(palette, dither)
+}
+
+// MapToPalette maps every pixel of the PPM image to the nearest color of a fixed palette (1 to 256 colors).
+// The palette colors are expressed in the same range as the image (0 to max).
+// Unless dither is DitherNone, the mapping error is spread with the chosen dithering method.
+func (ppm *PPM) MapToPalette(palette []Pixel, dither DitherMethod) (*Quantized, error) {
+	if len(palette) < 1 || len(palette) > 256 {
+		return nil, fmt.Errorf("Invalid palette size: %d (must be between 1 and 256)", len(palette))
+	}
+
+	result := &Quantized{
+		Palette: append([]Pixel(nil), palette...),
+		Indices: make([][]uint8, ppm.height),
+		Image: &PPM{
+			data:        make([][]Pixel, ppm.height),
+			width:       ppm.width,
+			height:      ppm.height,
+			magicNumber: ppm.magicNumber,
+			max:         ppm.max,
+		},
+	}
+	for y := 0; y < ppm.height; y++ {
+		result.Indices[y] = make([]uint8, ppm.width)
+		result.Image.data[y] = make([]Pixel, ppm.width)
+	}
+
+	switch dither {
+	case DitherNone:
+		cache := make(map[Pixel]uint8) // Most images reuse the same colors many times
+		for y := 0; y < ppm.height; y++ {
+			for x := 0; x < ppm.width; x++ {
+				pixel := ppm.data[y][x]
+				index, ok := cache[pixel]
+				if !ok {
+					index = nearestPaletteIndex(palette, float64(pixel.R), float64(pixel.G), float64(pixel.B))
+					cache[pixel] = index
+				}
+				result.Indices[y][x] = index
+			}
+		}
+	case DitherBayer:
+		ppm.orderedPaletteDither(palette, result.Indices)
+	default:
+		kernel, ok := diffusionKernels[dither]
+		if !ok {
+			return nil, fmt.Errorf("Unsupported dither method: %d", dither)
+		}
+		ppm.diffusePaletteDither(palette, kernel, result.Indices)
+	}
+
+	// Rebuild the image from the palette and the index map
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			result.Image.data[y][x] = palette[result.Indices[y][x]]
+		}
+	}
+	return result, nil
+}
+
+// ReadGPL reads a GIMP palette (.gpl) file and returns its colors.
+func ReadGPL(filename string) ([]Pixel, error) {
+	content, err := os.ReadFile(filename) // Read the entire file content into memory
+	if err != nil {                       // Check for file read errors
+		return nil, err
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r", ""), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "GIMP Palette" { // Check the file signature
+		return nil, fmt.Errorf("Invalid GPL file format: missing GIMP Palette header")
+	}
+
+	var palette []Pixel
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimSpace(line)
+		// Skip empty lines, comments and the optional Name/Columns headers
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "Name:") || strings.HasPrefix(trimmed, "Columns:") {
+			continue
+		}
+
+		fields := strings.Fields(trimmed) // "R G B [name]"
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("Invalid GPL color line: %q", line)
+		}
+		var channels [3]uint8
+		for i := 0; i < 3; i++ {
+			value, err := strconv.Atoi(fields[i])
+			if err != nil { //error handling
+				return nil, fmt.Errorf("Error during conversion: %v", err)
+			}
+			if value < 0 || value > 255 {
+				return nil, fmt.Errorf("Invalid GPL color value: %d", value)
+			}
+			channels[i] = uint8(value)
+		}
+		palette = append(palette, Pixel{R: channels[0], G: channels[1], B: channels[2]})
+	}
+
+	if len(palette) == 0 {
+		return nil, fmt.Errorf("Invalid GPL file format: no colors")
+	}
+	return palette, nil
+}
+
+// distinctColors returns every distinct color of the image along with its number of occurrences.
+func (ppm *PPM) distinctColors() []colorCount {
+	counts := make(map[Pixel]int)
+	for _, row := range ppm.data {
+		for _, pixel := range row {
+			counts[pixel]++
+		}
+	}
+
+	colors := make([]colorCount, 0, len(counts))
+	for color, count := range counts {
+		colors = append(colors, colorCount{color, count})
+	}
+	// Sort for a deterministic result, map iteration order being random
+	sort.Slice(colors, func(i, j int) bool {
+		a, b := colors[i].color, colors[j].color
+		if a.R != b.R {
+			return a.R < b.R
+		}
+		if a.G != b.G {
+			return a.G < b.G
+		}
+		return a.B < b.B
+	})
+	return colors
+}
+
+// channelValue returns the red (0), green (1) or blue (2) channel of a pixel.
+func channelValue(pixel Pixel, channel int) uint8 {
+	switch channel {
+	case 0:
+		return pixel.R
+	case 1:
+		return pixel.G
+	default:
+		return pixel.B
+	}
+}
+
+// medianCut builds a palette of at most n colors by recursively splitting the box with the widest channel range.
+func medianCut(colors []colorCount, n int) []Pixel {
+	if len(colors) == 0 {
+		return nil
+	}
+
+	boxes := [][]colorCount{colors}
+	for len(boxes) < n {
+		// Find the box with the widest range over any channel
+		bestBox, bestChannel, bestRange := -1, 0, 0
+		for boxIndex, box := range boxes {
+			if len(box) < 2 { // A single color cannot be split
+				continue
+			}
+			for channel := 0; channel < 3; channel++ {
+				low, high := uint8(255), uint8(0)
+				for _, entry := range box {
+					value := channelValue(entry.color, channel)
+					low, high = min(low, value), max(high, value)
+				}
+				if int(high)-int(low) > bestRange || bestBox == -1 {
+					bestBox, bestChannel, bestRange = boxIndex, channel, int(high)-int(low)
+				}
+			}
+		}
+		if bestBox == -1 { // Every box holds a single color
+			break
+		}
+
+		// Split the box at the weighted median along its widest channel
+		box := boxes[bestBox]
+		sort.SliceStable(box, func(i, j int) bool {
+			return channelValue(box[i].color, bestChannel) < channelValue(box[j].color, bestChannel)
+		})
+		total := 0
+		for _, entry := range box {
+			total += entry.count
+		}
+		split, running := 1, 0
+		for i, entry := range box[:len(box)-1] {
+			running += entry.count
+			split = i + 1
+			if running*2 >= total {
+				break
+			}
+		}
+		boxes[bestBox] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+
+	// Each box contributes the weighted average of its colors
+	palette := make([]Pixel, len(boxes))
+	for i, box := range boxes {
+		palette[i] = averageColor(box)
+	}
+	return palette
+}
+
+// averageColor returns the average of the given colors weighted by their counts.
+func averageColor(colors []colorCount) Pixel {
+	var sumR, sumG, sumB, total float64
+	for _, entry := range colors {
+		weight := float64(entry.count)
+		sumR += float64(entry.color.R) * weight
+		sumG += float64(entry.color.G) * weight
+		sumB += float64(entry.color.B) * weight
+		total += weight
+	}
+	if total == 0 {
+		return Pixel{}
+	}
+	return Pixel{
+		R: uint8(math.Round(sumR / total)),
+		G: uint8(math.Round(sumG / total)),
+		B: uint8(math.Round(sumB / total)),
+	}
+}
+
+// kMeans refines an initial palette with Lloyd's algorithm for at most the given number of iterations.
+func kMeans(colors []colorCount, palette []Pixel, iterations int) []Pixel {
+	palette = append([]Pixel(nil), palette...)
+	for iteration := 0; iteration < iterations; iteration++ {
+		clusters := make([][]colorCount, len(palette))
+		for _, entry := range colors { // Assign every color to its nearest center
+			index := nearestPaletteIndex(palette, float64(entry.color.R), float64(entry.color.G), float64(entry.color.B))
+			clusters[index] = append(clusters[index], entry)
+		}
+
+		changed := false
+		for i, cluster := range clusters { // Move every center to the mean of its cluster
+			if len(cluster) == 0 { // Keep empty clusters where they are
+				continue
+			}
+			center := averageColor(cluster)
+			if center != palette[i] {
+				palette[i] = center
+				changed = true
+			}
+		}
+		if !changed { // The centers are stable
+			break
+		}
+	}
+	return palette
+}
+
+// octreeNode is a node of the octree used for color quantization.
+type octreeNode struct {
+	children         [8]*octreeNode // Sub-cubes indexed by one bit of each channel
+	sumR, sumG, sumB int            // Sum of the colors falling in this node
+	count            int            // Number of pixels falling in this node
+	leaf             bool           // Whether the node is a leaf of the tree
+}
+
+// octreePalette builds a palette of at most n colors by merging octree leaves, deepest first.
+func (ppm *PPM) octreePalette(n int) []Pixel {
+	const depth = 8 // One level per bit of each channel
+	root := &octreeNode{}
+	levels := make([][]*octreeNode, depth) // Inner nodes at each depth, candidates for reduction
+	leafCount := 0
+
+	for _, entry := range ppm.distinctColors() { // Insert every distinct color with its weight
+		node := root
+		for level := 0; level < depth; level++ {
+			shift := uint(7 - level)
+			index := (int(entry.color.R>>shift)&1)<<2 | (int(entry.color.G>>shift)&1)<<1 | int(entry.color.B>>shift)&1
+			if node.children[index] == nil {
+				child := &octreeNode{leaf: level == depth-1}
+				node.children[index] = child
+				if child.leaf {
+					leafCount++
+				} else {
+					levels[level+1] = append(levels[level+1], child)
+				}
+			}
+			node = node.children[index]
+		}
+		node.sumR += int(entry.color.R) * entry.count
+		node.sumG += int(entry.color.G) * entry.count
+		node.sumB += int(entry.color.B) * entry.count
+		node.count += entry.count
+	}
+
+	// Merge the children of the deepest inner nodes until the number of leaves fits
+	for level := depth - 1; level >= 1 && leafCount > n; level-- {
+		nodes := levels[level]
+		// Reduce the least used nodes first to preserve the dominant colors
+		sort.SliceStable(nodes, func(i, j int) bool { return subtreeCount(nodes[i]) < subtreeCount(nodes[j]) })
+		for _, node := range nodes {
+			if leafCount <= n {
+				break
+			}
+			children := 0
+			for i, child := range node.children {
+				if child == nil {
+					continue
+				}
+				node.sumR += child.sumR
+				node.sumG += child.sumG
+				node.sumB += child.sumB
+				node.count += child.count
+				node.children[i] = nil
+				children++
+			}
+			node.leaf = true
+			leafCount -= children - 1
+		}
+	}
+
+	// Collect the leaves as weighted colors
+	var leaves []colorCount
+	var collect func(node *octreeNode)
+	collect = func(node *octreeNode) {
+		if node.leaf {
+			leaves = append(leaves, colorCount{
+				color: Pixel{
+					R: uint8(node.sumR / node.count),
+					G: uint8(node.sumG / node.count),
+					B: uint8(node.sumB / node.count),
+				},
+				count: node.count,
+			})
+			return
+		}
+		for _, child := range node.children {
+			if child != nil {
+				collect(child)
+			}
+		}
+	}
+	collect(root)
+
+	// The first level holds up to 8 leaves: merge the closest pairs if fewer colors were requested
+	for len(leaves) > n {
+		bestI, bestJ, bestDistance := 0, 1, math.Inf(1)
+		for i := range leaves {
+			for j := i + 1; j < len(leaves); j++ {
+				a, b := leaves[i].color, leaves[j].color
+				dr, dg, db := float64(a.R)-float64(b.R), float64(a.G)-float64(b.G), float64(a.B)-float64(b.B)
+				if distance := dr*dr + dg*dg + db*db; distance < bestDistance {
+					bestI, bestJ, bestDistance = i, j, distance
+				}
+			}
+		}
+		leaves[bestI] = colorCount{
+			color: averageColor([]colorCount{leaves[bestI], leaves[bestJ]}),
+			count: leaves[bestI].count + leaves[bestJ].count,
+		}
+		leaves = append(leaves[:bestJ], leaves[bestJ+1:]...)
+	}
+
+	palette := make([]Pixel, len(leaves))
+	for i, leaf := range leaves {
+		palette[i] = leaf.color
+	}
+	return palette
+}
+
+// subtreeCount returns the number of pixels below an octree node.
+func subtreeCount(node *octreeNode) int {
+	if node.leaf {
+		return node.count
+	}
+	total := 0
+	for _, child := range node.children {
+		if child != nil {
+			total += subtreeCount(child)
+		}
+	}
+	return total
+}
+
+// nearestPaletteIndex returns the index of the palette color closest to (r, g, b) in Euclidean RGB distance.
+func nearestPaletteIndex(palette []Pixel, r, g, b float64) uint8 {
+	bestIndex, bestDistance := 0, math.Inf(1)
+	for i, color := range palette {
+		dr, dg, db := r-float64(color.R), g-float64(color.G), b-float64(color.B)
+		distance := dr*dr + dg*dg + db*db
+		if distance < bestDistance {
+			bestIndex, bestDistance = i, distance
+		}
+	}
+	return uint8(bestIndex)
+}
+
+// diffusePaletteDither maps the image to the palette while spreading the error of each channel with the kernel.
+func (ppm *PPM) diffusePaletteDither(palette []Pixel, kernel []diffusionWeight, indices [][]uint8) {
+	// Working copy of the image in floating point, one value per channel
+	values := make([][][3]float64, ppm.height)
+	for y := range values {
+		values[y] = make([][3]float64, ppm.width)
+		for x, pixel := range ppm.data[y] {
+			values[y][x] = [3]float64{float64(pixel.R), float64(pixel.G), float64(pixel.B)}
+		}
+	}
+
+	maxFloat := float64(ppm.max)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			// Clamp the accumulated value so that the error cannot run away
+			current := values[y][x]
+			for channel := range current {
+				current[channel] = math.Min(math.Max(current[channel], 0), maxFloat)
+			}
+
+			index := nearestPaletteIndex(palette, current[0], current[1], current[2])
+			indices[y][x] = index
+			chosen := palette[index]
+			quantError := [3]float64{
+				current[0] - float64(chosen.R),
+				current[1] - float64(chosen.G),
+				current[2] - float64(chosen.B),
+			}
+
+			// Spread the error over the neighbors not yet visited
+			for _, entry := range kernel {
+				nx, ny := x+entry.dx, y+entry.dy
+				if nx >= 0 && nx < ppm.width && ny < ppm.height {
+					for channel := range quantError {
+						values[ny][nx][channel] += quantError[channel] * entry.weight
+					}
+				}
+			}
+		}
+	}
+}
+
+// orderedPaletteDither maps the image to the palette after offsetting each pixel by a 4x4 Bayer threshold.
+func (ppm *PPM) orderedPaletteDither(palette []Pixel, indices [][]uint8) {
+	matrix, _ := bayerMatrix(4)
+	size := len(matrix)
+	cells := float64(size * size)
+
+	// The offset amplitude matches the average spacing between palette colors along one channel
+	spread := float64(ppm.max) / math.Cbrt(float64(len(palette)))
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			offset := ((float64(matrix[y%size][x%size])+0.5)/cells - 0.5) * spread
+			pixel := ppm.data[y][x]
+			indices[y][x] = nearestPaletteIndex(palette, float64(pixel.R)+offset, float64(pixel.G)+offset, float64(pixel.B)+offset)
+		}
+	}
+}
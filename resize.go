@@ -0,0 +1,300 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// ResampleFilter selects how pixel values are computed when an image is resampled.
+type ResampleFilter int
+
+const (
+	ResampleNearest  ResampleFilter = iota // Value of the closest source pixel
+	ResampleBilinear                       // Linear interpolation between the 2x2 closest pixels
+	ResampleBicubic                        // Catmull-Rom cubic interpolation over the 4x4 closest pixels
+	ResampleLanczos                        // Lanczos windowed sinc over the 6x6 closest pixels, the sharpest
+	ResampleArea                           // Average of the source pixels covered by the output pixel, for thumbnails
+)
+
+// AspectMode selects how the aspect ratio is handled when resizing to a given size.
+type AspectMode int
+
+const (
+	AspectExact AspectMode = iota // Stretch the image to exactly the given size
+	AspectFit                     // Keep the aspect ratio, the result fitting inside the given size
+	AspectFill                    // Keep the aspect ratio, cover the given size and crop the overflow evenly
+)
+
+// contribution lists the source pixels used for one output pixel and their weights.
+type contribution struct {
+	indices []int
+	weights []float64
+}
+
+// Resize resamples the PGM image to the given size with the given filter.
+func (pgm *PGM) Resize(width, height int, filter ResampleFilter, aspect AspectMode) error {
+	scaledWidth, scaledHeight, err := resizeTarget(pgm.width, pgm.height, width, height, aspect)
+	if err != nil {
+		return err
+	}
+	plane, err := resamplePlane(uint8PlaneToFloat(pgm.data), scaledWidth, scaledHeight, filter)
+	if err != nil {
+		return err
+	}
+	pgm.data = floatPlaneToUint8(plane, pgm.max)
+	pgm.width, pgm.height = scaledWidth, scaledHeight
+	if aspect == AspectFill {
+		pgm.data = cropCenter(pgm.data, width, height)
+		pgm.width, pgm.height = width, height
+	}
+	return nil
+}
+
+// Resize resamples the PPM image to the given size with the given filter.
+func (ppm *PPM) Resize(width, height int, filter ResampleFilter, aspect AspectMode) error {
+	scaledWidth, scaledHeight, err := resizeTarget(ppm.width, ppm.height, width, height, aspect)
+	if err != nil {
+		return err
+	}
+	var planes [3][][]uint8
+	for channel := 0; channel < 3; channel++ {
+		plane, err := resamplePlane(uint8PlaneToFloat(ppm.channelPlane(channel)), scaledWidth, scaledHeight, filter)
+		if err != nil {
+			return err
+		}
+		planes[channel] = floatPlaneToUint8(plane, ppm.max)
+		if aspect == AspectFill {
+			planes[channel] = cropCenter(planes[channel], width, height)
+		}
+	}
+
+	if aspect == AspectFill {
+		scaledWidth, scaledHeight = width, height
+	}
+	ppm.width, ppm.height = scaledWidth, scaledHeight
+	ppm.data = make([][]Pixel, scaledHeight)
+	for y := range ppm.data {
+		ppm.data[y] = make([]Pixel, scaledWidth)
+	}
+	for channel, plane := range planes {
+		ppm.setChannelPlane(channel, plane)
+	}
+	return nil
+}
+
+// Resize resamples the PBM image to the given size. With majority, an output pixel is set when at least half of
+// the area it covers is set, which keeps thin strokes when shrinking; otherwise the closest source pixel is used.
+func (pbm *PBM) Resize(width, height int, majority bool, aspect AspectMode) error {
+	scaledWidth, scaledHeight, err := resizeTarget(pbm.width, pbm.height, width, height, aspect)
+	if err != nil {
+		return err
+	}
+	filter := ResampleNearest
+	if majority {
+		filter = ResampleArea
+	}
+	plane, err := resamplePlane(pbm.floatPlane(), scaledWidth, scaledHeight, filter)
+	if err != nil {
+		return err
+	}
+
+	data := thresholdPlane(plane)
+	if aspect == AspectFill {
+		data = cropCenter(data, width, height)
+		scaledWidth, scaledHeight = width, height
+	}
+	pbm.data, pbm.width, pbm.height = data, scaledWidth, scaledHeight
+	return nil
+}
+
+// resizeTarget returns the size the image is scaled to before a possible crop.
+func resizeTarget(sourceWidth, sourceHeight, width, height int, aspect AspectMode) (int, int, error) {
+	if width < 1 || height < 1 {
+		return 0, 0, fmt.Errorf("Invalid target size: %dx%d", width, height)
+	}
+	if sourceWidth < 1 || sourceHeight < 1 {
+		return 0, 0, fmt.Errorf("Cannot resize an empty image")
+	}
+
+	scaleX := float64(width) / float64(sourceWidth)
+	scaleY := float64(height) / float64(sourceHeight)
+	switch aspect {
+	case AspectExact:
+		return width, height, nil
+	case AspectFit:
+		scale := math.Min(scaleX, scaleY)
+		return max(int(math.Round(float64(sourceWidth)*scale)), 1), max(int(math.Round(float64(sourceHeight)*scale)), 1), nil
+	case AspectFill:
+		scale := math.Max(scaleX, scaleY)
+		return max(int(math.Round(float64(sourceWidth)*scale)), width), max(int(math.Round(float64(sourceHeight)*scale)), height), nil
+	}
+	return 0, 0, fmt.Errorf("Unsupported aspect mode: %d", aspect)
+}
+
+// cropCenter keeps the centered width x height part of a plane at least that large.
+func cropCenter[T any](plane [][]T, width, height int) [][]T {
+	top := (len(plane) - height) / 2
+	left := (len(plane[0]) - width) / 2
+	result := make([][]T, height)
+	for y := range result {
+		result[y] = append([]T(nil), plane[top+y][left:left+width]...)
+	}
+	return result
+}
+
+// resamplePlane resamples a plane to the given size, first along the rows then along the columns.
+func resamplePlane(plane [][]float64, width, height int, filter ResampleFilter) ([][]float64, error) {
+	sourceHeight, sourceWidth := len(plane), len(plane[0])
+	columns, err := contributions(sourceWidth, width, filter)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := contributions(sourceHeight, height, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Horizontal pass: every source row is resampled to the new width
+	horizontal := make([][]float64, sourceHeight)
+	parallelRows(sourceHeight, func(y int) {
+		horizontal[y] = make([]float64, width)
+		for x, contribution := range columns {
+			sum := 0.0
+			for i, index := range contribution.indices {
+				sum += plane[y][index] * contribution.weights[i]
+			}
+			horizontal[y][x] = sum
+		}
+	})
+
+	// Vertical pass: every output row mixes the resampled source rows
+	result := make([][]float64, height)
+	parallelRows(height, func(y int) {
+		result[y] = make([]float64, width)
+		contribution := rows[y]
+		for i, index := range contribution.indices {
+			weight := contribution.weights[i]
+			for x, value := range horizontal[index] {
+				result[y][x] += value * weight
+			}
+		}
+	})
+	return result, nil
+}
+
+// contributions computes, for each of the size output pixels along an axis, the weights of the source pixels.
+func contributions(sourceSize, size int, filter ResampleFilter) ([]contribution, error) {
+	scale := float64(sourceSize) / float64(size) // Source pixels per output pixel
+	result := make([]contribution, size)
+
+	switch filter {
+	case ResampleNearest:
+		for i := range result {
+			index := min(int((float64(i)+0.5)*scale), sourceSize-1)
+			result[i] = contribution{indices: []int{index}, weights: []float64{1}}
+		}
+		return result, nil
+
+	case ResampleArea:
+		// Weight each source pixel by its overlap with the output pixel
+		for i := range result {
+			start, end := float64(i)*scale, float64(i+1)*scale
+			for index := int(start); index < sourceSize && float64(index) < end; index++ {
+				overlap := math.Min(end, float64(index+1)) - math.Max(start, float64(index))
+				if overlap > 0 {
+					result[i].indices = append(result[i].indices, index)
+					result[i].weights = append(result[i].weights, overlap/scale)
+				}
+			}
+		}
+		return result, nil
+	}
+
+	kernel, support, err := resampleKernel(filter)
+	if err != nil {
+		return nil, err
+	}
+	// When shrinking, the kernel is stretched to cover all the source pixels and avoid aliasing
+	stretch := math.Max(scale, 1)
+	support *= stretch
+	for i := range result {
+		center := (float64(i)+0.5)*scale - 0.5
+		total := 0.0
+		for j := int(math.Ceil(center - support)); j <= int(math.Floor(center+support)); j++ {
+			weight := kernel((float64(j) - center) / stretch)
+			if weight == 0 {
+				continue
+			}
+			result[i].indices = append(result[i].indices, min(max(j, 0), sourceSize-1)) // Replicate the edges
+			result[i].weights = append(result[i].weights, weight)
+			total += weight
+		}
+		for k := range result[i].weights {
+			result[i].weights[k] /= total
+		}
+	}
+	return result, nil
+}
+
+// resampleKernel returns the interpolation kernel of a filter and its support (half width).
+func resampleKernel(filter ResampleFilter) (func(float64) float64, float64, error) {
+	switch filter {
+	case ResampleBilinear:
+		return func(x float64) float64 { return math.Max(1-math.Abs(x), 0) }, 1, nil
+	case ResampleBicubic:
+		return cubicKernel, 2, nil
+	case ResampleLanczos:
+		return lanczosKernel, 3, nil
+	}
+	return nil, 0, fmt.Errorf("Unsupported resampling filter: %d", filter)
+}
+
+// cubicKernel is the Catmull-Rom cubic (a = -0.5).
+func cubicKernel(x float64) float64 {
+	x = math.Abs(x)
+	switch {
+	case x < 1:
+		return 1.5*x*x*x - 2.5*x*x + 1
+	case x < 2:
+		return -0.5*x*x*x + 2.5*x*x - 4*x + 2
+	}
+	return 0
+}
+
+// lanczosKernel is the sinc function windowed by a sinc three times wider.
+func lanczosKernel(x float64) float64 {
+	switch {
+	case x == 0:
+		return 1
+	case math.Abs(x) >= 3:
+		return 0
+	}
+	px := math.Pi * x
+	return 3 * math.Sin(px) * math.Sin(px/3) / (px * px)
+}
+
+// parallelRows calls work for every row from 0 to count-1, spreading the rows over the available processors.
+func parallelRows(count int, work func(row int)) {
+	workers := min(runtime.GOMAXPROCS(0), count)
+	if workers <= 1 {
+		for row := 0; row < count; row++ {
+			work(row)
+		}
+		return
+	}
+
+	var group sync.WaitGroup
+	chunk := (count + workers - 1) / workers
+	for start := 0; start < count; start += chunk {
+		group.Add(1)
+		go func(start, end int) {
+			defer group.Done()
+			for row := start; row < end; row++ {
+				work(row)
+			}
+		}(start, min(start+chunk, count))
+	}
+	group.Wait()
+}
@@ -0,0 +1,300 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// Rotate90CW rotates the PBM image 90 degrees clockwise.
+func (pbm *PBM) Rotate90CW() {
+	pbm.data = reorientPlane(pbm.data, pbm.width, pbm.height, true, func(x, y int) (int, int) { return y, pbm.height - 1 - x })
+	pbm.width, pbm.height = pbm.height, pbm.width
+}
+
+// Rotate90CCW rotates the PBM image 90 degrees counter-clockwise.
+func (pbm *PBM) Rotate90CCW() {
+	pbm.data = reorientPlane(pbm.data, pbm.width, pbm.height, true, func(x, y int) (int, int) { return pbm.width - 1 - y, x })
+	pbm.width, pbm.height = pbm.height, pbm.width
+}
+
+// Rotate180 rotates the PBM image half a turn.
+func (pbm *PBM) Rotate180() {
+	pbm.data = reorientPlane(pbm.data, pbm.width, pbm.height, false, func(x, y int) (int, int) { return pbm.width - 1 - x, pbm.height - 1 - y })
+}
+
+// Transpose mirrors the PBM image along its main diagonal (top left to bottom right).
+func (pbm *PBM) Transpose() {
+	pbm.data = reorientPlane(pbm.data, pbm.width, pbm.height, true, func(x, y int) (int, int) { return y, x })
+	pbm.width, pbm.height = pbm.height, pbm.width
+}
+
+// Transverse mirrors the PBM image along its anti-diagonal (top right to bottom left).
+func (pbm *PBM) Transverse() {
+	pbm.data = reorientPlane(pbm.data, pbm.width, pbm.height, true, func(x, y int) (int, int) { return pbm.width - 1 - y, pbm.height - 1 - x })
+	pbm.width, pbm.height = pbm.height, pbm.width
+}
+
+// Rotate90CCW rotates the PGM image 90 degrees counter-clockwise.
+func (pgm *PGM) Rotate90CCW() {
+	pgm.data = reorientPlane(pgm.data, pgm.width, pgm.height, true, func(x, y int) (int, int) { return pgm.width - 1 - y, x })
+	pgm.width, pgm.height = pgm.height, pgm.width
+}
+
+// Rotate180 rotates the PGM image half a turn.
+func (pgm *PGM) Rotate180() {
+	pgm.data = reorientPlane(pgm.data, pgm.width, pgm.height, false, func(x, y int) (int, int) { return pgm.width - 1 - x, pgm.height - 1 - y })
+}
+
+// Transpose mirrors the PGM image along its main diagonal (top left to bottom right).
+func (pgm *PGM) Transpose() {
+	pgm.data = reorientPlane(pgm.data, pgm.width, pgm.height, true, func(x, y int) (int, int) { return y, x })
+	pgm.width, pgm.height = pgm.height, pgm.width
+}
+
+// Transverse mirrors the PGM image along its anti-diagonal (top right to bottom left).
+func (pgm *PGM) Transverse() {
+	pgm.data = reorientPlane(pgm.data, pgm.width, pgm.height, true, func(x, y int) (int, int) { return pgm.width - 1 - y, pgm.height - 1 - x })
+	pgm.width, pgm.height = pgm.height, pgm.width
+}
+
+// Rotate90CCW rotates the PPM image 90 degrees counter-clockwise.
+func (ppm *PPM) Rotate90CCW() {
+	ppm.data = reorientPlane(ppm.data, ppm.width, ppm.height, true, func(x, y int) (int, int) { return ppm.width - 1 - y, x })
+	ppm.width, ppm.height = ppm.height, ppm.width
+}
+
+// Rotate180 rotates the PPM image half a turn.
+func (ppm *PPM) Rotate180() {
+	ppm.data = reorientPlane(ppm.data, ppm.width, ppm.height, false, func(x, y int) (int, int) { return ppm.width - 1 - x, ppm.height - 1 - y })
+}
+
+// Transpose mirrors the PPM image along its main diagonal (top left to bottom right).
+func (ppm *PPM) Transpose() {
+	ppm.data = reorientPlane(ppm.data, ppm.width, ppm.height, true, func(x, y int) (int, int) { return y, x })
+	ppm.width, ppm.height = ppm.height, ppm.width
+}
+
+// Transverse mirrors the PPM image along its anti-diagonal (top right to bottom left).
+func (ppm *PPM) Transverse() {
+	ppm.data = reorientPlane(ppm.data, ppm.width, ppm.height, true, func(x, y int) (int, int) { return ppm.width - 1 - y, ppm.height - 1 - x })
+	ppm.width, ppm.height = ppm.height, ppm.width
+}
+
+// Rotate rotates the PBM image by angle degrees, counter-clockwise as displayed, around its center.
+// With expand, the canvas grows to hold the whole rotated image; otherwise it keeps its size and the corners
+// are cropped. Uncovered pixels take the background value. An empty image can only be rotated by multiples of 90 degrees.
+func (pbm *PBM) Rotate(angle float64, filter ResampleFilter, background bool, expand bool) error {
+	if rotateOrthogonal(angle, expand, pbm.width, pbm.height, pbm.Rotate90CCW) {
+		return nil
+	}
+	width, height, source, err := rotationMapping(pbm.width, pbm.height, angle, expand)
+	if err != nil {
+		return err
+	}
+	pbm.warp(width, height, filter, background, source)
+	return nil
+}
+
+// Rotate rotates the PGM image by angle degrees, counter-clockwise as displayed, around its center.
+// With expand, the canvas grows to hold the whole rotated image; otherwise it keeps its size and the corners
+// are cropped. Uncovered pixels take the background value. An empty image can only be rotated by multiples of 90 degrees.
+func (pgm *PGM) Rotate(angle float64, filter ResampleFilter, background uint8, expand bool) error {
+	if rotateOrthogonal(angle, expand, pgm.width, pgm.height, pgm.Rotate90CCW) {
+		return nil
+	}
+	width, height, source, err := rotationMapping(pgm.width, pgm.height, angle, expand)
+	if err != nil {
+		return err
+	}
+	pgm.warp(width, height, filter, background, source)
+	return nil
+}
+
+// Rotate rotates the PPM image by angle degrees, counter-clockwise as displayed, around its center.
+// With expand, the canvas grows to hold the whole rotated image; otherwise it keeps its size and the corners
+// are cropped. Uncovered pixels take the background color. An empty image can only be rotated by multiples of 90 degrees.
+func (ppm *PPM) Rotate(angle float64, filter ResampleFilter, background Pixel, expand bool) error {
+	if rotateOrthogonal(angle, expand, ppm.width, ppm.height, ppm.Rotate90CCW) {
+		return nil
+	}
+	width, height, source, err := rotationMapping(ppm.width, ppm.height, angle, expand)
+	if err != nil {
+		return err
+	}
+	ppm.warp(width, height, filter, background, source)
+	return nil
+}
+
+// rotateOrthogonal performs exact rotations by a multiple of 90 degrees that keep the whole image, calling
+// rotate90CCW once per quarter turn. It returns false when the angle needs resampling.
+func rotateOrthogonal(angle float64, expand bool, width, height int, rotate90CCW func()) bool {
+	quarters, ok := quarterTurns(angle, expand || width == height)
+	if ok {
+		for i := 0; i < quarters; i++ {
+			rotate90CCW()
+		}
+	}
+	return ok
+}
+
+// quarterTurns returns the number (0 to 3) of counter-clockwise quarter turns equal to the angle, if it is a
+// multiple of 90 degrees. Without sizeFree, only half turns qualify since quarter turns would change the size.
+func quarterTurns(angle float64, sizeFree bool) (int, bool) {
+	turns := angle / 90
+	if turns != math.Round(turns) {
+		return 0, false
+	}
+	quarters := ((int(turns) % 4) + 4) % 4
+	if quarters%2 == 1 && !sizeFree {
+		return 0, false
+	}
+	return quarters, true
+}
+
+// rotationMapping returns the size of the rotated canvas and the function mapping each of its pixels to a
+// position in the source image.
+func rotationMapping(sourceWidth, sourceHeight int, angle float64, expand bool) (int, int, func(x, y float64) (float64, float64), error) {
+	if sourceWidth < 1 || sourceHeight < 1 {
+		return 0, 0, nil, fmt.Errorf("Cannot rotate an empty image")
+	}
+	radians := angle * math.Pi / 180
+	cos, sin := math.Cos(radians), math.Sin(radians)
+
+	width, height := sourceWidth, sourceHeight
+	if expand {
+		// Bounding box of the rotated image, ignoring rounding noise on exact sizes
+		width = int(math.Ceil(math.Abs(float64(sourceWidth)*cos) + math.Abs(float64(sourceHeight)*sin) - 1e-9))
+		height = int(math.Ceil(math.Abs(float64(sourceWidth)*sin) + math.Abs(float64(sourceHeight)*cos) - 1e-9))
+	}
+
+	sourceCenterX, sourceCenterY := float64(sourceWidth-1)/2, float64(sourceHeight-1)/2
+	centerX, centerY := float64(width-1)/2, float64(height-1)/2
+	return width, height, func(x, y float64) (float64, float64) {
+		// Inverse rotation, rows growing downwards
+		dx, dy := x-centerX, y-centerY
+		return sourceCenterX + dx*cos - dy*sin, sourceCenterY + dx*sin + dy*cos
+	}, nil
+}
+
+// warpPlanes builds width x height planes by sampling the source planes with the filter at the positions given
+// by source. Positions outside of the source images take the background values.
+func warpPlanes(planes [][][]float64, background []float64, width, height int, filter ResampleFilter, source func(x, y float64) (float64, float64)) [][][]float64 {
+	sourceHeight, sourceWidth := len(planes[0]), len(planes[0][0])
+	result := make([][][]float64, len(planes))
+	for i := range result {
+		result[i] = make([][]float64, height)
+	}
+
+	parallelRows(height, func(y int) {
+		for i := range result {
+			result[i][y] = make([]float64, width)
+		}
+		for x := 0; x < width; x++ {
+			sx, sy := source(float64(x), float64(y))
+			if !(sx > -8 && sx < float64(sourceWidth+8) && sy > -8 && sy < float64(sourceHeight+8)) { // Far away, or NaN
+				for i := range result {
+					result[i][y][x] = background[i]
+				}
+				continue
+			}
+			startX, weightsX := sampleWeights(sx, filter)
+			startY, weightsY := sampleWeights(sy, filter)
+			for i, plane := range planes {
+				sum, total := 0.0, 0.0
+				for ky, weightY := range weightsY {
+					row := startY + ky
+					for kx, weightX := range weightsX {
+						col := startX + kx
+						value := background[i]
+						if col >= 0 && col < sourceWidth && row >= 0 && row < sourceHeight {
+							value = plane[row][col]
+						}
+						sum += value * weightX * weightY
+						total += weightX * weightY
+					}
+				}
+				result[i][y][x] = background[i]
+				if total != 0 {
+					result[i][y][x] = sum / total
+				}
+			}
+		}
+	})
+	return result
+}
+
+// sampleWeights returns the first source index and the weights of the pixels used to interpolate at the
+// given coordinate (pixel centers being at integer coordinates). Area averaging falls back to bilinear.
+func sampleWeights(coordinate float64, filter ResampleFilter) (int, []float64) {
+	if filter == ResampleNearest {
+		return int(math.Floor(coordinate + 0.5)), []float64{1}
+	}
+	kernel, support, err := resampleKernel(filter)
+	if err != nil { // ResampleArea, or an unknown filter
+		kernel, support, _ = resampleKernel(ResampleBilinear)
+	}
+	taps := int(2 * support)
+	start := int(math.Floor(coordinate)) - taps/2 + 1
+	weights := make([]float64, taps)
+	for k := range weights {
+		weights[k] = kernel(coordinate - float64(start+k))
+	}
+	return start, weights
+}
+
+// reorientPlane returns a copy of the plane moved by an orthogonal transform, where source gives the position
+// in the original plane of each pixel of the result. With swap, the result is height x width pixels.
+func reorientPlane[T any](data [][]T, width, height int, swap bool, source func(x, y int) (int, int)) [][]T {
+	if swap {
+		width, height = height, width
+	}
+	result := make([][]T, height)
+	for y := range result {
+		result[y] = make([]T, width)
+		for x := range result[y] {
+			sx, sy := source(x, y)
+			result[y][x] = data[sy][sx]
+		}
+	}
+	return result
+}
+
+// floatPlane returns the PBM image as a plane where set pixels are 1 and the others 0.
+func (pbm *PBM) floatPlane() [][]float64 {
+	plane := make([][]float64, pbm.height)
+	for y := range plane {
+		plane[y] = make([]float64, pbm.width)
+		for x := range plane[y] {
+			if pbm.data[y][x] {
+				plane[y][x] = 1
+			}
+		}
+	}
+	return plane
+}
+
+// thresholdPlane sets the pixels of a plane having at least half of the full value 1.
+func thresholdPlane(plane [][]float64) [][]bool {
+	result := make([][]bool, len(plane))
+	for y, row := range plane {
+		result[y] = make([]bool, len(row))
+		for x, value := range row {
+			result[y][x] = value >= 0.5-1e-9 // Tolerate rounding in the weights
+		}
+	}
+	return result
+}
+
+// reallocate replaces the pixels of the PPM image with width x height black pixels.
+func (ppm *PPM) reallocate(width, height int) {
+	ppm.width, ppm.height = width, height
+	ppm.data = make([][]Pixel, height)
+	for y := range ppm.data {
+		ppm.data[y] = make([]Pixel, width)
+	}
+}
+
+// pixelToFloats returns the channels of a pixel as float values.
+func pixelToFloats(pixel Pixel) []float64 {
+	return []float64{float64(pixel.R), float64(pixel.G), float64(pixel.B)}
+}
@@ -0,0 +1,248 @@
+package Netpbm
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newTestPGM returns a 3x2 PGM image whose pixels all have distinct values:
+//
+//	1 2 3
+//	4 5 6
+func newTestPGM() *PGM {
+	return &PGM{
+		data:        [][]uint8{{1, 2, 3}, {4, 5, 6}},
+		width:       3,
+		height:      2,
+		magicNumber: "P2",
+		max:         255,
+	}
+}
+
+// newTestPPM returns a 3x2 PPM image whose pixels all have distinct red values, laid out as newTestPGM.
+func newTestPPM() *PPM {
+	ppm := &PPM{data: make([][]Pixel, 2), width: 3, height: 2, magicNumber: "P3", max: 255}
+	for y := range ppm.data {
+		ppm.data[y] = make([]Pixel, 3)
+		for x := range ppm.data[y] {
+			value := uint8(y*3 + x + 1)
+			ppm.data[y][x] = Pixel{R: value, G: 10 * value, B: 255 - value}
+		}
+	}
+	return ppm
+}
+
+// newTestPBM returns a 3x2 PBM image with a single set pixel in its top left corner.
+func newTestPBM() *PBM {
+	return &PBM{
+		data:        [][]bool{{true, false, false}, {false, false, false}},
+		width:       3,
+		height:      2,
+		magicNumber: "P1",
+	}
+}
+
+// redPlane returns the red channel of a PPM image.
+func redPlane(ppm *PPM) [][]uint8 {
+	plane := make([][]uint8, ppm.height)
+	for y := range plane {
+		plane[y] = make([]uint8, ppm.width)
+		for x := range plane[y] {
+			plane[y][x] = ppm.data[y][x].R
+		}
+	}
+	return plane
+}
+
+func TestOrthogonalRotationsPGM(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform func(*PGM)
+		want      [][]uint8
+	}{
+		{"Rotate90CW", (*PGM).Rotate90CW, [][]uint8{{4, 1}, {5, 2}, {6, 3}}},
+		{"Rotate90CCW", (*PGM).Rotate90CCW, [][]uint8{{3, 6}, {2, 5}, {1, 4}}},
+		{"Rotate180", (*PGM).Rotate180, [][]uint8{{6, 5, 4}, {3, 2, 1}}},
+		{"Transpose", (*PGM).Transpose, [][]uint8{{1, 4}, {2, 5}, {3, 6}}},
+		{"Transverse", (*PGM).Transverse, [][]uint8{{6, 3}, {5, 2}, {4, 1}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pgm := newTestPGM()
+			test.transform(pgm)
+			if !reflect.DeepEqual(pgm.data, test.want) {
+				t.Errorf("data = %v, want %v", pgm.data, test.want)
+			}
+			if width, height := pgm.Size(); width != len(test.want[0]) || height != len(test.want) {
+				t.Errorf("size = %dx%d, want %dx%d", width, height, len(test.want[0]), len(test.want))
+			}
+		})
+	}
+}
+
+func TestOrthogonalRotationsPPM(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform func(*PPM)
+		want      [][]uint8
+	}{
+		{"Rotate90CW", (*PPM).Rotate90CW, [][]uint8{{4, 1}, {5, 2}, {6, 3}}},
+		{"Rotate90CCW", (*PPM).Rotate90CCW, [][]uint8{{3, 6}, {2, 5}, {1, 4}}},
+		{"Rotate180", (*PPM).Rotate180, [][]uint8{{6, 5, 4}, {3, 2, 1}}},
+		{"Transpose", (*PPM).Transpose, [][]uint8{{1, 4}, {2, 5}, {3, 6}}},
+		{"Transverse", (*PPM).Transverse, [][]uint8{{6, 3}, {5, 2}, {4, 1}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ppm := newTestPPM()
+			test.transform(ppm)
+			if got := redPlane(ppm); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("red channel = %v, want %v", got, test.want)
+			}
+			if width, height := ppm.Size(); width != len(test.want[0]) || height != len(test.want) {
+				t.Errorf("size = %dx%d, want %dx%d", width, height, len(test.want[0]), len(test.want))
+			}
+			// Whole pixels move together
+			for _, row := range ppm.data {
+				for _, pixel := range row {
+					if pixel.G != 10*pixel.R || pixel.B != 255-pixel.R {
+						t.Fatalf("pixel %v mixes channels of different pixels", pixel)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestOrthogonalRotationsPBM(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform func(*PBM)
+		want      [][]bool
+	}{
+		{"Rotate90CW", (*PBM).Rotate90CW, [][]bool{{false, true}, {false, false}, {false, false}}},
+		{"Rotate90CCW", (*PBM).Rotate90CCW, [][]bool{{false, false}, {false, false}, {true, false}}},
+		{"Rotate180", (*PBM).Rotate180, [][]bool{{false, false, false}, {false, false, true}}},
+		{"Transpose", (*PBM).Transpose, [][]bool{{true, false}, {false, false}, {false, false}}},
+		{"Transverse", (*PBM).Transverse, [][]bool{{false, false}, {false, false}, {false, true}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pbm := newTestPBM()
+			test.transform(pbm)
+			if !reflect.DeepEqual(pbm.data, test.want) {
+				t.Errorf("data = %v, want %v", pbm.data, test.want)
+			}
+			if width, height := pbm.Size(); width != len(test.want[0]) || height != len(test.want) {
+				t.Errorf("size = %dx%d, want %dx%d", width, height, len(test.want[0]), len(test.want))
+			}
+		})
+	}
+}
+
+func TestRotateInverses(t *testing.T) {
+	pgm := newTestPGM()
+	pgm.Rotate90CW()
+	pgm.Rotate90CCW()
+	if want := newTestPGM(); !reflect.DeepEqual(pgm.data, want.data) {
+		t.Errorf("Rotate90CW then Rotate90CCW = %v, want %v", pgm.data, want.data)
+	}
+
+	ppm := newTestPPM()
+	for i := 0; i < 4; i++ {
+		ppm.Rotate90CW()
+	}
+	if want := newTestPPM(); !reflect.DeepEqual(ppm.data, want.data) {
+		t.Errorf("four Rotate90CW = %v, want %v", ppm.data, want.data)
+	}
+
+	pbm := newTestPBM()
+	pbm.Transpose()
+	pbm.Transpose()
+	if want := newTestPBM(); !reflect.DeepEqual(pbm.data, want.data) {
+		t.Errorf("Transpose twice = %v, want %v", pbm.data, want.data)
+	}
+}
+
+func TestRotateOrthogonalAngles(t *testing.T) {
+	tests := []struct {
+		angle float64
+		want  func(*PGM)
+	}{
+		{90, (*PGM).Rotate90CCW},
+		{-90, (*PGM).Rotate90CW},
+		{270, (*PGM).Rotate90CW},
+		{180, (*PGM).Rotate180},
+	}
+	for _, test := range tests {
+		for _, filter := range []ResampleFilter{ResampleNearest, ResampleBilinear, ResampleBicubic} {
+			got, want := newTestPGM(), newTestPGM()
+			if err := got.Rotate(test.angle, filter, 0, true); err != nil {
+				t.Fatal(err)
+			}
+			test.want(want)
+			if !reflect.DeepEqual(got.data, want.data) {
+				t.Errorf("Rotate(%v, %d) = %v, want %v", test.angle, filter, got.data, want.data)
+			}
+		}
+	}
+}
+
+func TestRotateArbitraryAngle(t *testing.T) {
+	// A 40x10 white bar, turned through the resampling path
+	pgm := &PGM{data: make([][]uint8, 10), width: 40, height: 10, magicNumber: "P2", max: 255}
+	for y := range pgm.data {
+		pgm.data[y] = make([]uint8, 40)
+		for x := range pgm.data[y] {
+			pgm.data[y][x] = 255
+		}
+	}
+
+	rotated := *pgm
+	if err := rotated.Rotate(45, ResampleBilinear, 0, true); err != nil {
+		t.Fatal(err)
+	}
+	if rotated.width != 36 || rotated.height != 36 {
+		t.Errorf("expanded size = %dx%d, want 36x36", rotated.width, rotated.height)
+	}
+	if center := rotated.data[18][18]; center != 255 {
+		t.Errorf("center = %d, want 255", center)
+	}
+	if corner := rotated.data[0][0]; corner != 0 {
+		t.Errorf("corner = %d, want the background", corner)
+	}
+
+	cropped := *pgm
+	if err := cropped.Rotate(30, ResampleNearest, 7, false); err != nil {
+		t.Fatal(err)
+	}
+	if cropped.width != 40 || cropped.height != 10 {
+		t.Errorf("cropped size = %dx%d, want 40x10", cropped.width, cropped.height)
+	}
+	if corner := cropped.data[0][0]; corner != 7 {
+		t.Errorf("corner = %d, want the background 7", corner)
+	}
+
+	pbm := &PBM{data: newMask(6, 2), width: 6, height: 2, magicNumber: "P1"}
+	if err := pbm.Rotate(45, ResampleNearest, true, true); err != nil {
+		t.Fatal(err)
+	}
+	if pbm.width != 6 || pbm.height != 6 || !pbm.data[0][0] {
+		t.Errorf("PBM rotated to %dx%d with corner %v, want 6x6 with a set background", pbm.width, pbm.height, pbm.data[0][0])
+	}
+}
+
+func TestRotateEmptyImage(t *testing.T) {
+	pgm := &PGM{magicNumber: "P2", max: 255}
+	if err := pgm.Rotate(30, ResampleBilinear, 0, true); err == nil {
+		t.Errorf("Rotate of an empty PGM image succeeded, want an error")
+	}
+	ppm := &PPM{magicNumber: "P3", max: 255}
+	if err := ppm.Rotate(45, ResampleNearest, Pixel{}, false); err == nil {
+		t.Errorf("Rotate of an empty PPM image succeeded, want an error")
+	}
+	pbm := &PBM{magicNumber: "P1"}
+	if err := pbm.Rotate(10, ResampleNearest, false, true); err == nil {
+		t.Errorf("Rotate of an empty PBM image succeeded, want an error")
+	}
+}
@@ -0,0 +1,321 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Channel is a set of PPM channels affected by an operation.
+type Channel int
+
+const (
+	ChannelRed   Channel = 1 << iota // Red channel
+	ChannelGreen                     // Green channel
+	ChannelBlue                      // Blue channel
+
+	ChannelAll = ChannelRed | ChannelGreen | ChannelBlue // All three channels
+)
+
+// indices returns the indices of the selected channels, 0 standing for red, 1 for green and 2 for blue.
+func (c Channel) indices() []int {
+	var indices []int
+	for index := 0; index < 3; index++ {
+		if c&(1<<index) != 0 {
+			indices = append(indices, index)
+		}
+	}
+	return indices
+}
+
+// BrightnessContrast adjusts the brightness and contrast of the PGM image.
+// Both values range from -1 to 1: brightness adds a fraction of max, contrast scales the values around the middle gray.
+func (pgm *PGM) BrightnessContrast(brightness, contrast float64) {
+	pgm.applyLookup(brightnessContrastLookup(pgm.max, brightness, contrast))
+}
+
+// BrightnessContrast adjusts the brightness and contrast of the selected channels of the PPM image.
+func (ppm *PPM) BrightnessContrast(brightness, contrast float64, channels Channel) {
+	ppm.applyLookup(brightnessContrastLookup(ppm.max, brightness, contrast), channels)
+}
+
+// Gamma applies a gamma correction to the PGM image: values above 1 brighten the midtones, values below 1 darken them.
+func (pgm *PGM) Gamma(gamma float64) {
+	pgm.applyLookup(gammaLookup(pgm.max, gamma))
+}
+
+// Gamma applies a gamma correction to the selected channels of the PPM image.
+func (ppm *PPM) Gamma(gamma float64, channels Channel) {
+	ppm.applyLookup(gammaLookup(ppm.max, gamma), channels)
+}
+
+// Levels maps the input range [inputBlack, inputWhite] to the output range [outputBlack, outputWhite] of the PGM image.
+// midtone is a gamma applied in between (1 leaves the midtones unchanged).
+func (pgm *PGM) Levels(inputBlack, inputWhite int, midtone float64, outputBlack, outputWhite int) error {
+	lookup, err := levelsLookup(pgm.max, inputBlack, inputWhite, midtone, outputBlack, outputWhite)
+	if err != nil {
+		return err
+	}
+	pgm.applyLookup(lookup)
+	return nil
+}
+
+// Levels maps the input range to the output range on the selected channels of the PPM image.
+func (ppm *PPM) Levels(inputBlack, inputWhite int, midtone float64, outputBlack, outputWhite int, channels Channel) error {
+	lookup, err := levelsLookup(ppm.max, inputBlack, inputWhite, midtone, outputBlack, outputWhite)
+	if err != nil {
+		return err
+	}
+	ppm.applyLookup(lookup, channels)
+	return nil
+}
+
+// Curves remaps the PGM image through a smooth monotone curve passing by the control points.
+// Each point maps an input value (X) to an output value (Y), both between 0 and max.
+func (pgm *PGM) Curves(points []Point) error {
+	lookup, err := curveLookup(pgm.max, points)
+	if err != nil {
+		return err
+	}
+	pgm.applyLookup(lookup)
+	return nil
+}
+
+// Curves remaps the selected channels of the PPM image through a smooth monotone curve passing by the control points.
+func (ppm *PPM) Curves(points []Point, channels Channel) error {
+	lookup, err := curveLookup(ppm.max, points)
+	if err != nil {
+		return err
+	}
+	ppm.applyLookup(lookup, channels)
+	return nil
+}
+
+// ApplyLUT remaps the PGM image through a lookup table of 256 (8-bit) or 65536 (16-bit) entries.
+// Values are scaled from 0..max to the table range and back.
+func (pgm *PGM) ApplyLUT(lut []int) error {
+	lookup, err := tableLookup(pgm.max, lut)
+	if err != nil {
+		return err
+	}
+	pgm.applyLookup(lookup)
+	return nil
+}
+
+// ApplyLUT remaps the selected channels of the PPM image through a lookup table of 256 or 65536 entries.
+func (ppm *PPM) ApplyLUT(lut []int, channels Channel) error {
+	lookup, err := tableLookup(ppm.max, lut)
+	if err != nil {
+		return err
+	}
+	ppm.applyLookup(lookup, channels)
+	return nil
+}
+
+// AutoLevels stretches the PGM image so that the darkest lowPercent and brightest highPercent of the pixels are clipped
+// to 0 and max, the rest being spread over the full range.
+func (pgm *PGM) AutoLevels(lowPercent, highPercent float64) {
+	low, high := percentileRange(planeHistogram(pgm.data, pgm.max), lowPercent, highPercent)
+	lookup, _ := levelsLookup(pgm.max, low, high, 1, 0, pgm.max)
+	pgm.applyLookup(lookup)
+}
+
+// AutoLevels stretches each selected channel of the PPM image independently with percentile clipping.
+func (ppm *PPM) AutoLevels(lowPercent, highPercent float64, channels Channel) {
+	for _, channel := range channels.indices() {
+		plane := ppm.channelPlane(channel)
+		low, high := percentileRange(planeHistogram(plane, ppm.max), lowPercent, highPercent)
+		lookup, _ := levelsLookup(ppm.max, low, high, 1, 0, ppm.max)
+		ppm.applyLookup(lookup, 1<<channel)
+	}
+}
+
+// applyLookup replaces every value of the PGM image by its entry in the lookup table.
+func (pgm *PGM) applyLookup(lookup []uint8) {
+	for rowIndex := 0; rowIndex < pgm.height; rowIndex++ {
+		for colIndex := 0; colIndex < pgm.width; colIndex++ {
+			pgm.data[rowIndex][colIndex] = lookup[min(int(pgm.data[rowIndex][colIndex]), pgm.max)]
+		}
+	}
+}
+
+// applyLookup replaces every value of the selected channels of the PPM image by its entry in the lookup table.
+func (ppm *PPM) applyLookup(lookup []uint8, channels Channel) {
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			pixel := &ppm.data[y][x]
+			if channels&ChannelRed != 0 {
+				pixel.R = lookup[min(int(pixel.R), ppm.max)]
+			}
+			if channels&ChannelGreen != 0 {
+				pixel.G = lookup[min(int(pixel.G), ppm.max)]
+			}
+			if channels&ChannelBlue != 0 {
+				pixel.B = lookup[min(int(pixel.B), ppm.max)]
+			}
+		}
+	}
+}
+
+// buildLookup builds a lookup table over 0..maxValue from a function of normalized values (0 to 1).
+func buildLookup(maxValue int, transfer func(float64) float64) []uint8 {
+	lookup := make([]uint8, maxValue+1)
+	for value := range lookup {
+		normalized := transfer(float64(value) / float64(maxValue))
+		normalized = math.Min(math.Max(normalized, 0), 1) // Clamp to the valid range
+		lookup[value] = uint8(math.Round(normalized * float64(maxValue)))
+	}
+	return lookup
+}
+
+// brightnessContrastLookup builds the lookup table of a brightness/contrast adjustment.
+func brightnessContrastLookup(maxValue int, brightness, contrast float64) []uint8 {
+	contrast = math.Min(math.Max(contrast, -1), 0.999) // A contrast of 1 would be an infinite slope
+	factor := (1 + contrast) / (1 - contrast)
+	return buildLookup(maxValue, func(v float64) float64 {
+		return (v-0.5)*factor + 0.5 + brightness
+	})
+}
+
+// gammaLookup builds the lookup table of a gamma correction.
+func gammaLookup(maxValue int, gamma float64) []uint8 {
+	if gamma <= 0 { // Invalid gamma: leave the values unchanged
+		gamma = 1
+	}
+	return buildLookup(maxValue, func(v float64) float64 {
+		return math.Pow(v, 1/gamma)
+	})
+}
+
+// levelsLookup builds the lookup table of an input/output levels adjustment.
+func levelsLookup(maxValue, inputBlack, inputWhite int, midtone float64, outputBlack, outputWhite int) ([]uint8, error) {
+	if inputBlack < 0 || inputWhite > maxValue || inputBlack >= inputWhite {
+		return nil, fmt.Errorf("Invalid input levels: %d to %d (max %d)", inputBlack, inputWhite, maxValue)
+	}
+	if outputBlack < 0 || outputWhite > maxValue || outputBlack > maxValue || outputWhite < 0 {
+		return nil, fmt.Errorf("Invalid output levels: %d to %d (max %d)", outputBlack, outputWhite, maxValue)
+	}
+	if midtone <= 0 {
+		midtone = 1
+	}
+
+	maxFloat := float64(maxValue)
+	low, high := float64(inputBlack)/maxFloat, float64(inputWhite)/maxFloat
+	outLow, outHigh := float64(outputBlack)/maxFloat, float64(outputWhite)/maxFloat
+	return buildLookup(maxValue, func(v float64) float64 {
+		v = math.Min(math.Max((v-low)/(high-low), 0), 1) // Normalize the input range
+		v = math.Pow(v, 1/midtone)                       // Midtone gamma
+		return outLow + v*(outHigh-outLow)               // Spread over the output range
+	}), nil
+}
+
+// curveLookup builds the lookup table of a monotone cubic curve (Fritsch–Carlson) through the control points.
+func curveLookup(maxValue int, points []Point) ([]uint8, error) {
+	if len(points) < 2 {
+		return nil, fmt.Errorf("Invalid curve: at least 2 control points are required")
+	}
+	sorted := append([]Point(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].X < sorted[j].X })
+	for i, point := range sorted {
+		if point.X < 0 || point.X > maxValue || point.Y < 0 || point.Y > maxValue {
+			return nil, fmt.Errorf("Invalid curve point: (%d, %d) is out of 0..%d", point.X, point.Y, maxValue)
+		}
+		if i > 0 && point.X == sorted[i-1].X {
+			return nil, fmt.Errorf("Invalid curve: two control points share the input %d", point.X)
+		}
+	}
+
+	// Secant slopes between consecutive points
+	n := len(sorted)
+	secants := make([]float64, n-1)
+	for i := 0; i < n-1; i++ {
+		secants[i] = float64(sorted[i+1].Y-sorted[i].Y) / float64(sorted[i+1].X-sorted[i].X)
+	}
+
+	// Tangents at each point, limited so that the curve does not overshoot
+	tangents := make([]float64, n)
+	tangents[0], tangents[n-1] = secants[0], secants[n-2]
+	for i := 1; i < n-1; i++ {
+		if secants[i-1]*secants[i] <= 0 {
+			tangents[i] = 0
+		} else {
+			tangents[i] = (secants[i-1] + secants[i]) / 2
+		}
+	}
+	for i := 0; i < n-1; i++ {
+		if secants[i] == 0 {
+			tangents[i], tangents[i+1] = 0, 0
+			continue
+		}
+		alpha, beta := tangents[i]/secants[i], tangents[i+1]/secants[i]
+		if norm := alpha*alpha + beta*beta; norm > 9 {
+			scale := 3 / math.Sqrt(norm)
+			tangents[i], tangents[i+1] = scale*alpha*secants[i], scale*beta*secants[i]
+		}
+	}
+
+	lookup := make([]uint8, maxValue+1)
+	segment := 0
+	for value := range lookup {
+		var output float64
+		switch {
+		case value <= sorted[0].X: // Flat before the first point
+			output = float64(sorted[0].Y)
+		case value >= sorted[n-1].X: // Flat after the last point
+			output = float64(sorted[n-1].Y)
+		default:
+			for value > sorted[segment+1].X {
+				segment++
+			}
+			// Cubic Hermite interpolation on the segment
+			x0, x1 := float64(sorted[segment].X), float64(sorted[segment+1].X)
+			y0, y1 := float64(sorted[segment].Y), float64(sorted[segment+1].Y)
+			h := x1 - x0
+			t := (float64(value) - x0) / h
+			t2, t3 := t*t, t*t*t
+			output = (2*t3-3*t2+1)*y0 + (t3-2*t2+t)*h*tangents[segment] + (-2*t3+3*t2)*y1 + (t3-t2)*h*tangents[segment+1]
+		}
+		lookup[value] = uint8(math.Round(math.Min(math.Max(output, 0), float64(maxValue))))
+	}
+	return lookup, nil
+}
+
+// tableLookup converts a 256 or 65536 entry lookup table into a lookup table over 0..maxValue.
+func tableLookup(maxValue int, lut []int) ([]uint8, error) {
+	if len(lut) != 256 && len(lut) != 65536 {
+		return nil, fmt.Errorf("Invalid LUT size: %d (must be 256 or 65536)", len(lut))
+	}
+	tableMax := float64(len(lut) - 1)
+
+	lookup := make([]uint8, maxValue+1)
+	for value := range lookup {
+		index := int(math.Round(float64(value) * tableMax / float64(maxValue))) // Input scaled to the table range
+		output := math.Min(math.Max(float64(lut[index]), 0), tableMax)
+		lookup[value] = uint8(math.Round(output * float64(maxValue) / tableMax)) // Output scaled back to 0..max
+	}
+	return lookup, nil
+}
+
+// percentileRange returns the values below which lowPercent and above which highPercent of the pixels lie.
+func percentileRange(histogram []int, lowPercent, highPercent float64) (int, int) {
+	cumulative := cumulate(histogram)
+	total := float64(cumulative[len(cumulative)-1])
+
+	low, high := 0, len(histogram)-1
+	for level, value := range cumulative {
+		if float64(value) > total*lowPercent/100 {
+			low = level
+			break
+		}
+	}
+	for level := len(cumulative) - 1; level > 0; level-- {
+		if float64(cumulative[level-1]) < total*(1-highPercent/100) {
+			high = level
+			break
+		}
+	}
+	if high <= low { // Uniform image: nothing to stretch
+		return 0, len(histogram) - 1
+	}
+	return low, high
+}
@@ -0,0 +1,280 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// Matrix is a 2D affine transform mapping (x, y) to (A*x + B*y + C, D*x + E*y + F).
+// Coordinates are in pixels, rows growing downwards and pixel centers at integer positions.
+type Matrix struct {
+	A, B, C float64
+	D, E, F float64
+}
+
+// Homography is a 2D perspective transform, as a 3x3 matrix acting on homogeneous coordinates (x, y, 1).
+type Homography [3][3]float64
+
+// IdentityTransform returns the transform leaving every point in place.
+func IdentityTransform() Matrix {
+	return Matrix{A: 1, E: 1}
+}
+
+// TranslationMatrix returns the transform moving every point by (tx, ty).
+func TranslationMatrix(tx, ty float64) Matrix {
+	return Matrix{A: 1, C: tx, E: 1, F: ty}
+}
+
+// ScaleMatrix returns the transform scaling by sx horizontally and sy vertically around the origin.
+func ScaleMatrix(sx, sy float64) Matrix {
+	return Matrix{A: sx, E: sy}
+}
+
+// RotationMatrix returns the transform rotating by angle degrees around the origin, counter-clockwise as displayed.
+func RotationMatrix(angle float64) Matrix {
+	radians := angle * math.Pi / 180
+	cos, sin := math.Cos(radians), math.Sin(radians)
+	return Matrix{A: cos, B: sin, D: -sin, E: cos}
+}
+
+// ShearMatrix returns the transform shifting x by shx times y and y by shy times x.
+func ShearMatrix(shx, shy float64) Matrix {
+	return Matrix{A: 1, B: shx, D: shy, E: 1}
+}
+
+// Then returns the transform applying m first, then next.
+func (m Matrix) Then(next Matrix) Matrix {
+	return Matrix{
+		A: next.A*m.A + next.B*m.D,
+		B: next.A*m.B + next.B*m.E,
+		C: next.A*m.C + next.B*m.F + next.C,
+		D: next.D*m.A + next.E*m.D,
+		E: next.D*m.B + next.E*m.E,
+		F: next.D*m.C + next.E*m.F + next.F,
+	}
+}
+
+// Translate returns the transform applying m, then a translation by (tx, ty).
+func (m Matrix) Translate(tx, ty float64) Matrix {
+	return m.Then(TranslationMatrix(tx, ty))
+}
+
+// Scale returns the transform applying m, then a scaling by (sx, sy) around the origin.
+func (m Matrix) Scale(sx, sy float64) Matrix {
+	return m.Then(ScaleMatrix(sx, sy))
+}
+
+// Rotate returns the transform applying m, then a rotation by angle degrees around the origin.
+func (m Matrix) Rotate(angle float64) Matrix {
+	return m.Then(RotationMatrix(angle))
+}
+
+// Shear returns the transform applying m, then a shear by (shx, shy).
+func (m Matrix) Shear(shx, shy float64) Matrix {
+	return m.Then(ShearMatrix(shx, shy))
+}
+
+// RotateAround returns the transform applying m, then a rotation by angle degrees around the given center.
+func (m Matrix) RotateAround(angle float64, center FloatPoint) Matrix {
+	return m.Translate(-center.X, -center.Y).Rotate(angle).Translate(center.X, center.Y)
+}
+
+// Invert returns the transform undoing m.
+func (m Matrix) Invert() (Matrix, error) {
+	determinant := m.A*m.E - m.B*m.D
+	if math.Abs(determinant) < 1e-12 {
+		return Matrix{}, fmt.Errorf("The transform is not invertible")
+	}
+	return Matrix{
+		A: m.E / determinant,
+		B: -m.B / determinant,
+		C: (m.B*m.F - m.E*m.C) / determinant,
+		D: -m.D / determinant,
+		E: m.A / determinant,
+		F: (m.D*m.C - m.A*m.F) / determinant,
+	}, nil
+}
+
+// Apply returns the image of a point by the transform.
+func (m Matrix) Apply(point FloatPoint) FloatPoint {
+	return FloatPoint{m.A*point.X + m.B*point.Y + m.C, m.D*point.X + m.E*point.Y + m.F}
+}
+
+// Homography returns the affine transform as a perspective transform.
+func (m Matrix) Homography() Homography {
+	return Homography{{m.A, m.B, m.C}, {m.D, m.E, m.F}, {0, 0, 1}}
+}
+
+// HomographyFromPoints returns the perspective transform mapping each source point to the matching destination
+// point. To straighten a photographed document, give its corners as source and the corners of the output
+// rectangle as destination.
+func HomographyFromPoints(source, destination [4]FloatPoint) (Homography, error) {
+	// Each correspondence gives two linear equations on the eight unknown coefficients (the last one being 1)
+	var system [8][9]float64
+	for i := 0; i < 4; i++ {
+		x, y := source[i].X, source[i].Y
+		u, v := destination[i].X, destination[i].Y
+		system[2*i] = [9]float64{x, y, 1, 0, 0, 0, -u * x, -u * y, u}
+		system[2*i+1] = [9]float64{0, 0, 0, x, y, 1, -v * x, -v * y, v}
+	}
+
+	// Gaussian elimination with partial pivoting
+	for column := 0; column < 8; column++ {
+		pivot := column
+		for row := column + 1; row < 8; row++ {
+			if math.Abs(system[row][column]) > math.Abs(system[pivot][column]) {
+				pivot = row
+			}
+		}
+		if math.Abs(system[pivot][column]) < 1e-12 {
+			return Homography{}, fmt.Errorf("The points do not define a perspective transform (three of them are aligned)")
+		}
+		system[column], system[pivot] = system[pivot], system[column]
+		for row := 0; row < 8; row++ {
+			if row == column {
+				continue
+			}
+			factor := system[row][column] / system[column][column]
+			for k := column; k < 9; k++ {
+				system[row][k] -= factor * system[column][k]
+			}
+		}
+	}
+
+	var coefficients [9]float64
+	for i := 0; i < 8; i++ {
+		coefficients[i] = system[i][8] / system[i][i]
+	}
+	coefficients[8] = 1
+	return Homography{
+		{coefficients[0], coefficients[1], coefficients[2]},
+		{coefficients[3], coefficients[4], coefficients[5]},
+		{coefficients[6], coefficients[7], coefficients[8]},
+	}, nil
+}
+
+// Apply returns the image of a point by the transform. It returns false for points sent to infinity.
+func (h Homography) Apply(point FloatPoint) (FloatPoint, bool) {
+	w := h[2][0]*point.X + h[2][1]*point.Y + h[2][2]
+	if math.Abs(w) < 1e-12 {
+		return FloatPoint{}, false
+	}
+	return FloatPoint{
+		X: (h[0][0]*point.X + h[0][1]*point.Y + h[0][2]) / w,
+		Y: (h[1][0]*point.X + h[1][1]*point.Y + h[1][2]) / w,
+	}, true
+}
+
+// Invert returns the transform undoing h.
+func (h Homography) Invert() (Homography, error) {
+	// Inverse through the adjugate matrix
+	cofactor := func(r1, r2, c1, c2 int) float64 { return h[r1][c1]*h[r2][c2] - h[r1][c2]*h[r2][c1] }
+	adjugate := Homography{
+		{cofactor(1, 2, 1, 2), -cofactor(0, 2, 1, 2), cofactor(0, 1, 1, 2)},
+		{-cofactor(1, 2, 0, 2), cofactor(0, 2, 0, 2), -cofactor(0, 1, 0, 2)},
+		{cofactor(1, 2, 0, 1), -cofactor(0, 2, 0, 1), cofactor(0, 1, 0, 1)},
+	}
+	determinant := h[0][0]*adjugate[0][0] + h[0][1]*adjugate[1][0] + h[0][2]*adjugate[2][0]
+	if math.Abs(determinant) < 1e-12 {
+		return Homography{}, fmt.Errorf("The transform is not invertible")
+	}
+	for row := range adjugate {
+		for column := range adjugate[row] {
+			adjugate[row][column] /= determinant
+		}
+	}
+	return adjugate, nil
+}
+
+// Warp applies the affine transform to the PBM image, producing a width x height image.
+// Pixels whose source lies outside of the image take the background value.
+func (pbm *PBM) Warp(matrix Matrix, width, height int, filter ResampleFilter, background bool) error {
+	return pbm.WarpPerspective(matrix.Homography(), width, height, filter, background)
+}
+
+// Warp applies the affine transform to the PGM image, producing a width x height image.
+// Pixels whose source lies outside of the image take the background value.
+func (pgm *PGM) Warp(matrix Matrix, width, height int, filter ResampleFilter, background uint8) error {
+	return pgm.WarpPerspective(matrix.Homography(), width, height, filter, background)
+}
+
+// Warp applies the affine transform to the PPM image, producing a width x height image.
+// Pixels whose source lies outside of the image take the background color.
+func (ppm *PPM) Warp(matrix Matrix, width, height int, filter ResampleFilter, background Pixel) error {
+	return ppm.WarpPerspective(matrix.Homography(), width, height, filter, background)
+}
+
+// WarpPerspective applies the perspective transform to the PBM image, producing a width x height image.
+func (pbm *PBM) WarpPerspective(homography Homography, width, height int, filter ResampleFilter, background bool) error {
+	source, err := inverseMapping(homography, pbm.width, pbm.height, width, height)
+	if err != nil {
+		return err
+	}
+	pbm.warp(width, height, filter, background, source)
+	return nil
+}
+
+// WarpPerspective applies the perspective transform to the PGM image, producing a width x height image.
+func (pgm *PGM) WarpPerspective(homography Homography, width, height int, filter ResampleFilter, background uint8) error {
+	source, err := inverseMapping(homography, pgm.width, pgm.height, width, height)
+	if err != nil {
+		return err
+	}
+	pgm.warp(width, height, filter, background, source)
+	return nil
+}
+
+// WarpPerspective applies the perspective transform to the PPM image, producing a width x height image.
+func (ppm *PPM) WarpPerspective(homography Homography, width, height int, filter ResampleFilter, background Pixel) error {
+	source, err := inverseMapping(homography, ppm.width, ppm.height, width, height)
+	if err != nil {
+		return err
+	}
+	ppm.warp(width, height, filter, background, source)
+	return nil
+}
+
+// inverseMapping checks the source and output sizes and returns the function giving the source position of each
+// output pixel.
+func inverseMapping(homography Homography, sourceWidth, sourceHeight, width, height int) (func(x, y float64) (float64, float64), error) {
+	if sourceWidth < 1 || sourceHeight < 1 {
+		return nil, fmt.Errorf("Cannot warp an empty image")
+	}
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("Invalid output size: %dx%d", width, height)
+	}
+	inverse, err := homography.Invert()
+	if err != nil {
+		return nil, err
+	}
+	return func(x, y float64) (float64, float64) {
+		point, ok := inverse.Apply(FloatPoint{x, y})
+		if !ok { // Sent to infinity: outside of the image
+			return math.NaN(), math.NaN()
+		}
+		return point.X, point.Y
+	}, nil
+}
+
+// warp replaces the PBM image with a width x height image sampled at the positions given by source.
+func (pbm *PBM) warp(width, height int, filter ResampleFilter, background bool, source func(x, y float64) (float64, float64)) {
+	backgroundValue := 0.0
+	if background {
+		backgroundValue = 1
+	}
+	planes := warpPlanes([][][]float64{pbm.floatPlane()}, []float64{backgroundValue}, width, height, filter, source)
+	pbm.data, pbm.width, pbm.height = thresholdPlane(planes[0]), width, height
+}
+
+// warp replaces the PGM image with a width x height image sampled at the positions given by source.
+func (pgm *PGM) warp(width, height int, filter ResampleFilter, background uint8, source func(x, y float64) (float64, float64)) {
+	planes := warpPlanes([][][]float64{uint8PlaneToFloat(pgm.data)}, []float64{float64(background)}, width, height, filter, source)
+	pgm.data, pgm.width, pgm.height = floatPlaneToUint8(planes[0], pgm.max), width, height
+}
+
+// warp replaces the PPM image with a width x height image sampled at the positions given by source.
+func (ppm *PPM) warp(width, height int, filter ResampleFilter, background Pixel, source func(x, y float64) (float64, float64)) {
+	planes := warpPlanes(ppm.floatPlanes(), pixelToFloats(background), width, height, filter, source)
+	ppm.reallocate(width, height)
+	ppm.setFloatPlanes(planes)
+}